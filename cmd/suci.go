@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/keybackend"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+// suciCommand is the offline SUCI tooling: home network key generation, the
+// UE side concealment and the de-concealment with the keys of a config file.
+func suciCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "suci",
+		Usage: "Generate home network keys, conceal and de-conceal SUCIs offline",
+		Before: func(cliCtx *cli.Context) error {
+			// keep the output to the result
+			logger.Log.SetLevel(logrus.ErrorLevel)
+			return nil
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:   "genkey",
+				Usage:  "Generate a home network key pair, printed as a SuciProfile entry",
+				Action: suciGenKey,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "scheme",
+						Value: suci.ProfileAScheme,
+						Usage: "Protection scheme `ID`, 1 (Profile A), 2 (Profile B) or C (hybrid X25519+ML-KEM-768)",
+					},
+					&cli.IntFlag{
+						Name:  "key-id",
+						Usage: "Home network public key `ID` of the entry, 1-255",
+					},
+				},
+			},
+			{
+				Name:   "conceal",
+				Usage:  "Conceal a SUPI into a SUCI with a home network public key, as the UE does",
+				Action: suciConceal,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "supi",
+						Required: true,
+						Usage:    "`SUPI` to conceal, imsi-<imsi> or nai-<username>@<realm>",
+					},
+					&cli.StringFlag{
+						Name:  "scheme",
+						Value: suci.ProfileAScheme,
+						Usage: "Protection scheme `ID`, 0 (null scheme), 1, 2 or C",
+					},
+					&cli.IntFlag{
+						Name:  "key-id",
+						Value: 1,
+						Usage: "Home network public key `ID`",
+					},
+					&cli.StringFlag{
+						Name:  "public-key",
+						Usage: "Home network public key, `HEX` encoded",
+					},
+					&cli.IntFlag{
+						Name:  "mnc-len",
+						Value: 2,
+						Usage: "Number of digits of the MNC of an IMSI, 2 or 3",
+					},
+					&cli.StringFlag{
+						Name:  "routing-indicator",
+						Value: "0",
+						Usage: "Routing `INDICATOR`, 1-4 digits",
+					},
+				},
+			},
+			{
+				Name:      "deconceal",
+				Usage:     "De-conceal SUCIs with the SuciProfile keys of a config file",
+				ArgsUsage: "SUCI...",
+				Action:    suciDeconceal,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "config",
+						Aliases: []string{"c"},
+						Usage:   "Load configuration from `FILE`",
+					},
+				},
+			},
+		},
+	}
+}
+
+func suciGenKey(cliCtx *cli.Context) error {
+	scheme := strings.ToUpper(cliCtx.String("scheme"))
+	privateKey, publicKey, err := suci.GenerateKey(scheme)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal([]suci.SuciProfile{
+		{
+			KeyId:            cliCtx.Int("key-id"),
+			ProtectionScheme: scheme,
+			PrivateKey:       privateKey,
+			PublicKey:        publicKey,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(cliCtx.App.Writer, string(out))
+	return err
+}
+
+func suciConceal(cliCtx *cli.Context) error {
+	concealed, err := suci.Conceal(cliCtx.String("supi"), cliCtx.Int("mnc-len"), cliCtx.String("routing-indicator"),
+		cliCtx.String("scheme"), cliCtx.Int("key-id"), cliCtx.String("public-key"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cliCtx.App.Writer, concealed)
+	return err
+}
+
+func suciDeconceal(cliCtx *cli.Context) error {
+	if cliCtx.NArg() == 0 {
+		return fmt.Errorf("no SUCI given")
+	}
+	cfg, err := factory.ReadConfig(cliCtx.String("config"))
+	if err != nil {
+		return err
+	}
+	if pkcs11Cfg := cfg.GetPkcs11(); pkcs11Cfg != nil {
+		backend, err := keybackend.NewPkcs11Backend(pkcs11Cfg)
+		if err != nil {
+			return err
+		}
+		keybackend.Register(keybackend.Pkcs11, backend)
+		defer func() {
+			_ = keybackend.CloseAll()
+		}()
+	}
+
+	for _, concealed := range cliCtx.Args().Slice() {
+		supi, err := suci.ToSupi(concealed, cfg.Configuration.SuciProfiles)
+		if err != nil {
+			return fmt.Errorf("de-conceal [%s]: %w", concealed, err)
+		}
+		if _, err := fmt.Fprintln(cliCtx.App.Writer, supi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+const suciTestConfig = `info:
+  version: 1.0.3
+configuration:
+  sbi:
+    scheme: http
+    registerIPv4: 127.0.0.3
+    bindingIPv4: 127.0.0.3
+    port: 8000
+  serviceNameList:
+    - nudm-ueau
+  nrfUri: http://127.0.0.10:8000
+  SuciProfile:
+%s
+logger:
+  enable: true
+  level: info
+`
+
+func runSuciCommand(t *testing.T, args ...string) string {
+	var out bytes.Buffer
+	app := cli.NewApp()
+	app.Writer = &out
+	app.Commands = []*cli.Command{suciCommand()}
+	require.NoError(t, app.Run(append([]string{"udm", "suci"}, args...)))
+	return out.String()
+}
+
+func TestSuciCommand(t *testing.T) {
+	const supi = "imsi-208930000000001"
+
+	for _, scheme := range []string{"1", "2", "c"} {
+		t.Run(scheme, func(t *testing.T) {
+			genKeyOut := runSuciCommand(t, "genkey", "--scheme", scheme, "--key-id", "3")
+			var profiles []suci.SuciProfile
+			require.NoError(t, yaml.Unmarshal([]byte(genKeyOut), &profiles))
+			require.Len(t, profiles, 1)
+			require.Equal(t, strings.ToUpper(scheme), profiles[0].ProtectionScheme)
+			require.Equal(t, 3, profiles[0].KeyId)
+
+			concealed := strings.TrimSpace(runSuciCommand(t, "conceal", "--supi", supi, "--scheme", scheme,
+				"--key-id", "3", "--public-key", profiles[0].PublicKey))
+			require.True(t, strings.HasPrefix(concealed, "suci-0-208-93-0-"+strings.ToUpper(scheme)+"-3-"))
+
+			// the genkey output is the SuciProfile of the config
+			indented := "    " + strings.ReplaceAll(strings.TrimSpace(genKeyOut), "\n", "\n    ")
+			cfgPath := filepath.Join(t.TempDir(), "udmcfg.yaml")
+			require.NoError(t, os.WriteFile(cfgPath, []byte(fmt.Sprintf(suciTestConfig, indented)), 0o600))
+
+			require.Equal(t, supi, strings.TrimSpace(runSuciCommand(t, "deconceal", "-c", cfgPath, concealed)))
+		})
+	}
+}
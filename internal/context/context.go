@@ -2,13 +2,19 @@ package context
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 
 	"github.com/free5gc/openapi"
@@ -23,6 +29,9 @@ import (
 
 var udmContext = UDMContext{}
 
+// ErrRoutingIndicatorNotServed is returned for SUCIs of another UDM group
+var ErrRoutingIndicatorNotServed = errors.New("routing indicator is not served by this UDM")
+
 const (
 	LocationUriAmf3GppAccessRegistration int = iota
 	LocationUriAmfNon3GppAccessRegistration
@@ -39,6 +48,7 @@ func Init() {
 
 type NFContext interface {
 	AuthorizationCheck(token string, serviceName models.ServiceName) error
+	TokenSubject(token string) string
 }
 
 var _ NFContext = &UDMContext{}
@@ -59,8 +69,21 @@ type UDMContext struct {
 	SharedSubsDataMap              map[string]models.UdmSdmSharedData // sharedDataIds as key
 	SubscriptionOfSharedDataChange sync.Map                           // subscriptionID as key
 	SuciProfiles                   []suci.SuciProfile
+	RoutingIndicators              []string
+	suciCache                      *suciCache
 	EeSubscriptionIDGenerator      *idgenerator.IDGenerator
 	OAuth2Required                 bool
+	AllowedPlmnList                []models.PlmnId
+	OperatorKeys                   *factory.OperatorKeys
+	sqnLocksMu                     sync.Mutex
+	sqnLocks                       map[string]*sqnLock // supi as key
+	sqnAuditOnce                   sync.Once
+	sqnAuditLog                    *sqnAuditLog
+}
+
+type sqnLock struct {
+	sync.Mutex
+	refs int
 }
 
 type UdmUeContext struct {
@@ -72,6 +95,8 @@ type UdmUeContext struct {
 	AmfNon3GppAccessRegistration      *models.AmfNon3GppAccessRegistration
 	AccessAndMobilitySubscriptionData *models.AccessAndMobilitySubscriptionData
 	SmfSelSubsData                    *models.SmfSelectionSubscriptionData
+	SmsSubsData                       *models.SmsSubscriptionData
+	SmsMngSubsData                    *models.SmsManagementSubscriptionData
 	UeCtxtInSmfData                   *models.UeContextInSmfData
 	TraceDataResponse                 models.TraceDataResponse
 	TraceData                         *models.TraceData
@@ -85,7 +110,14 @@ type UdmUeContext struct {
 	EeSubscriptions                   map[string]*models.UdmEeEeSubscription // subscriptionID as key
 	amSubsDataLock                    sync.Mutex
 	smfSelSubsDataLock                sync.Mutex
+	smsSubsDataLock                   sync.Mutex
+	smsMngSubsDataLock                sync.Mutex
 	SmSubsDataLock                    sync.RWMutex
+	derivedOpc                        []byte
+	derivedOpcSource                  [sha256.Size]byte // hash of the K and OP the OPc was derived from
+	derivedOpcLock                    sync.Mutex
+	AuthEventLock                     sync.Mutex           // serializes writes of the authentication status in the UDR
+	indLastUse                        map[string]time.Time // guarded by UDMContext.LockSqn
 }
 
 func (ue *UdmUeContext) Init() {
@@ -136,6 +168,10 @@ func InitUdmContext(context *UDMContext) {
 	servingNameList := configuration.ServiceNameList
 
 	udmContext.SuciProfiles = configuration.SuciProfiles
+	udmContext.RoutingIndicators = configuration.RoutingIndicators
+	udmContext.suciCache = newSuciCache(configuration.SuciCache)
+	udmContext.AllowedPlmnList = configuration.AllowedPlmnList
+	udmContext.OperatorKeys = configuration.OperatorKeys
 
 	udmContext.InitNFService(servingNameList, config.Info.Version)
 }
@@ -275,6 +311,20 @@ func (udmUeContext *UdmUeContext) SetSmfSelectionSubsData(smfSelSubsData *models
 	udmUeContext.SmfSelSubsData = smfSelSubsData
 }
 
+// SetSmsSubsData ... functions to set SmsSubscriptionData
+func (udmUeContext *UdmUeContext) SetSmsSubsData(smsSubsData *models.SmsSubscriptionData) {
+	udmUeContext.smsSubsDataLock.Lock()
+	defer udmUeContext.smsSubsDataLock.Unlock()
+	udmUeContext.SmsSubsData = smsSubsData
+}
+
+// SetSmsMngSubsData ... functions to set SmsManagementSubscriptionData
+func (udmUeContext *UdmUeContext) SetSmsMngSubsData(smsMngSubsData *models.SmsManagementSubscriptionData) {
+	udmUeContext.smsMngSubsDataLock.Lock()
+	defer udmUeContext.smsMngSubsDataLock.Unlock()
+	udmUeContext.SmsMngSubsData = smsMngSubsData
+}
+
 // SetSMSubsData ... functions to set SessionManagementSubsData
 func (udmUeContext *UdmUeContext) SetSMSubsData(smSubsData map[string]models.SessionManagementSubscriptionData) {
 	udmUeContext.SmSubsDataLock.Lock()
@@ -282,6 +332,110 @@ func (udmUeContext *UdmUeContext) SetSMSubsData(smSubsData map[string]models.Ses
 	udmUeContext.SessionManagementSubsData = smSubsData
 }
 
+// GetDerivedOpc returns the cached OPc if it was derived from the same K and OP
+func (udmUeContext *UdmUeContext) GetDerivedOpc(k, op []byte) ([]byte, bool) {
+	udmUeContext.derivedOpcLock.Lock()
+	defer udmUeContext.derivedOpcLock.Unlock()
+	if udmUeContext.derivedOpc == nil || udmUeContext.derivedOpcSource != opcSourceHash(k, op) {
+		return nil, false
+	}
+	return udmUeContext.derivedOpc, true
+}
+
+// SetDerivedOpc caches the OPc derived from K and OP
+func (udmUeContext *UdmUeContext) SetDerivedOpc(k, op, opc []byte) {
+	udmUeContext.derivedOpcLock.Lock()
+	defer udmUeContext.derivedOpcLock.Unlock()
+	udmUeContext.derivedOpc = opc
+	udmUeContext.derivedOpcSource = opcSourceHash(k, op)
+}
+
+// IndLastUse returns when each IND key of the SQN was last used by this UDM.
+// Callers hold LockSqn of the UE's SUPI.
+func (udmUeContext *UdmUeContext) IndLastUse() map[string]time.Time {
+	if udmUeContext.indLastUse == nil {
+		udmUeContext.indLastUse = make(map[string]time.Time)
+	}
+	return udmUeContext.indLastUse
+}
+
+// AuthEventId identifies the authentication result stored in the UDR, so that a removal
+// request can be matched against the UDR content on any UDM instance. The timestamp is
+// truncated to milliseconds because that is the precision the UDR stores it with.
+func AuthEventId(supi string, authEvent *models.AuthEvent) string {
+	h := sha256.New()
+	var timeStamp int64
+	if authEvent.TimeStamp != nil {
+		timeStamp = authEvent.TimeStamp.UnixMilli()
+	}
+	fmt.Fprintf(h, "%s|%s|%t|%d|%s|%s", supi, authEvent.NfInstanceId, authEvent.Success, timeStamp,
+		authEvent.AuthType, authEvent.ServingNetworkName)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func opcSourceHash(k, op []byte) (sum [sha256.Size]byte) {
+	h := sha256.New()
+	h.Write(k)
+	h.Write(op)
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// LockSqn serializes the SQN allocation for supi within this UDM. The
+// returned function releases the lock.
+func (context *UDMContext) LockSqn(supi string) (unlock func()) {
+	context.sqnLocksMu.Lock()
+	if context.sqnLocks == nil {
+		context.sqnLocks = make(map[string]*sqnLock)
+	}
+	l, ok := context.sqnLocks[supi]
+	if !ok {
+		l = new(sqnLock)
+		context.sqnLocks[supi] = l
+	}
+	l.refs++
+	context.sqnLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		context.sqnLocksMu.Lock()
+		defer context.sqnLocksMu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(context.sqnLocks, supi)
+		}
+	}
+}
+
+// SuciToSupi de-conceals suciOrSupi, a SUPI is returned as is. SUCIs with a
+// routing indicator this UDM is not configured for are rejected, they belong
+// to another UDM group. With a SUCI cache, SUCIs seen recently are not
+// de-concealed again.
+func (context *UDMContext) SuciToSupi(suciOrSupi string) (string, error) {
+	routingIndicator, isSuci := suci.RoutingIndicator(suciOrSupi)
+	if isSuci && len(context.RoutingIndicators) > 0 && !slices.Contains(context.RoutingIndicators, routingIndicator) {
+		return "", fmt.Errorf("%w: %s", ErrRoutingIndicatorNotServed, routingIndicator)
+	}
+	if !isSuci || context.suciCache == nil {
+		return suci.ToSupi(suciOrSupi, context.SuciProfiles)
+	}
+
+	// a cached SUPI is only returned while the key it was de-concealed with is configured and valid
+	profile, err := suci.ProfileOf(suciOrSupi, context.SuciProfiles, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if supi, ok := context.suciCache.get(suciOrSupi, profile); ok {
+		return supi, nil
+	}
+	supi, err := suci.ToSupi(suciOrSupi, context.SuciProfiles)
+	if err != nil {
+		return "", err
+	}
+	context.suciCache.put(suciOrSupi, supi, profile)
+	return supi, nil
+}
+
 func (context *UDMContext) NewUdmUe(supi string) *UdmUeContext {
 	ue := new(UdmUeContext)
 	ue.Init()
@@ -411,6 +565,11 @@ func (ue *UdmUeContext) GetLocationURI(types int) string {
 	return ""
 }
 
+// GetAuthEventLocationURI returns the resource URI of the authentication result authEventId
+func (ue *UdmUeContext) GetAuthEventLocationURI(authEventId string) string {
+	return GetSelf().GetIPv4Uri() + factory.UdmUeauResUriPrefix + "/" + ue.Supi + "/auth-events/" + authEventId
+}
+
 func (ue *UdmUeContext) GetLocationURI2(types int, supi string) string {
 	switch types {
 	case LocationUriSharedDataSubscription:
@@ -518,3 +677,23 @@ func (context *UDMContext) AuthorizationCheck(token string, serviceName models.S
 	}
 	return nil
 }
+
+// TokenSubject returns the subject, i.e. the NF instance id of the consumer,
+// of an access token that passed AuthorizationCheck. It is empty without
+// OAuth2.
+func (context *UDMContext) TokenSubject(token string) string {
+	if !context.OAuth2Required {
+		return ""
+	}
+	fields := strings.Fields(token)
+	if len(fields) < 2 {
+		return ""
+	}
+	// the signature has been verified by AuthorizationCheck
+	claims := &models.NrfAccessTokenAccessTokenClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(fields[1], claims); err != nil {
+		logger.UtilLog.Debugf("UDMContext::TokenSubject: %+v", err)
+		return ""
+	}
+	return claims.Sub
+}
@@ -0,0 +1,95 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+func TestSuciToSupiRoutingIndicator(t *testing.T) {
+	udmContext := &UDMContext{}
+
+	// any routing indicator is served when none is configured
+	supi, err := udmContext.SuciToSupi("suci-0-208-93-1234-0-0-00007487")
+	require.NoError(t, err)
+	require.Equal(t, "imsi-2089300007487", supi)
+
+	udmContext.RoutingIndicators = []string{"0", "12"}
+	supi, err = udmContext.SuciToSupi("suci-0-208-93-12-0-0-00007487")
+	require.NoError(t, err)
+	require.Equal(t, "imsi-2089300007487", supi)
+
+	_, err = udmContext.SuciToSupi("suci-0-208-93-1234-0-0-00007487")
+	require.ErrorIs(t, err, ErrRoutingIndicatorNotServed)
+
+	// SUPIs carry no routing indicator
+	supi, err = udmContext.SuciToSupi("imsi-2089300007487")
+	require.NoError(t, err)
+	require.Equal(t, "imsi-2089300007487", supi)
+}
+
+func TestSuciToSupiCache(t *testing.T) {
+	udmContext := &UDMContext{suciCache: newSuciCache(&factory.SuciCache{})}
+
+	supi, err := udmContext.SuciToSupi("suci-0-208-93-0-0-0-00007487")
+	require.NoError(t, err)
+	require.Equal(t, "imsi-2089300007487", supi)
+	cached, ok := udmContext.suciCache.get("suci-0-208-93-0-0-0-00007487", suci.SuciProfile{})
+	require.True(t, ok)
+	require.Equal(t, supi, cached)
+
+	// failures and SUPIs are not cached
+	_, err = udmContext.SuciToSupi("suci-0-208-93-0-1-1-00007487")
+	require.Error(t, err)
+	_, err = udmContext.SuciToSupi("imsi-2089300007487")
+	require.NoError(t, err)
+	require.Len(t, udmContext.suciCache.entries, 1)
+}
+
+func TestSuciToSupiCacheKeyChange(t *testing.T) {
+	privateKey, publicKey, err := suci.GenerateKey(suci.ProfileAScheme)
+	require.NoError(t, err)
+	profile := suci.SuciProfile{
+		KeyId:            1,
+		ProtectionScheme: suci.ProfileAScheme,
+		PrivateKey:       privateKey,
+		PublicKey:        publicKey,
+	}
+	udmContext := &UDMContext{
+		SuciProfiles: []suci.SuciProfile{profile},
+		suciCache:    newSuciCache(&factory.SuciCache{}),
+	}
+
+	concealed, err := suci.Conceal("imsi-208930000000001", 2, "0", suci.ProfileAScheme, 1, publicKey)
+	require.NoError(t, err)
+	supi, err := udmContext.SuciToSupi(concealed)
+	require.NoError(t, err)
+	require.Equal(t, "imsi-208930000000001", supi)
+	require.Len(t, udmContext.suciCache.entries, 1)
+
+	// the cached SUPI is not returned once the key has expired
+	expired := profile
+	expired.NotAfter = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	udmContext.SuciProfiles = []suci.SuciProfile{expired}
+	_, err = udmContext.SuciToSupi(concealed)
+	require.ErrorIs(t, err, suci.ErrorUnknownPublicKey)
+
+	// nor once the key is removed
+	udmContext.SuciProfiles = nil
+	_, err = udmContext.SuciToSupi(concealed)
+	require.ErrorIs(t, err, suci.ErrorUnknownPublicKey)
+
+	// nor once the key id is given to another key
+	otherPrivateKey, otherPublicKey, err := suci.GenerateKey(suci.ProfileAScheme)
+	require.NoError(t, err)
+	replaced := profile
+	replaced.PrivateKey, replaced.PublicKey = otherPrivateKey, otherPublicKey
+	udmContext.SuciProfiles = []suci.SuciProfile{replaced}
+	_, err = udmContext.SuciToSupi(concealed)
+	require.Error(t, err)
+	require.Empty(t, udmContext.suciCache.entries)
+}
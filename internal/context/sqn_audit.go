@@ -0,0 +1,96 @@
+package context
+
+import (
+	"sync"
+	"time"
+
+	"github.com/free5gc/udm/pkg/factory"
+)
+
+// Outcomes of an SQN audit record
+const (
+	SqnAuditIssued         = "issued"
+	SqnAuditPooled         = "pooled"
+	SqnAuditResynced       = "resynced"
+	SqnAuditMacFailure     = "mac_failure"
+	SqnAuditResyncRejected = "resync_rejected"
+	SqnAuditUpdateFailed   = "update_failed"
+)
+
+// SqnAuditRecord describes an issuance of authentication vectors or an SQN
+// re-synchronisation. SQNs are hex encoded.
+type SqnAuditRecord struct {
+	Time               time.Time `json:"time"`
+	Supi               string    `json:"supi"`
+	ServingNetworkName string    `json:"servingNetworkName,omitempty"`
+	RequesterNfId      string    `json:"requesterNfId,omitempty"`
+	Outcome            string    `json:"outcome"`
+	OldSqn             string    `json:"oldSqn,omitempty"`
+	NewSqn             string    `json:"newSqn,omitempty"`
+	SqnMs              string    `json:"sqnMs,omitempty"`
+	Sqns               []string  `json:"sqns,omitempty"`
+	Ind                *int32    `json:"ind,omitempty"`
+	Cause              string    `json:"cause,omitempty"`
+}
+
+// sqnAuditLog keeps the latest records in a ring buffer
+type sqnAuditLog struct {
+	mu      sync.Mutex
+	records []SqnAuditRecord
+	next    int
+	full    bool
+}
+
+func (l *sqnAuditLog) add(record SqnAuditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records[l.next] = record
+	if l.next++; l.next == len(l.records) {
+		l.next, l.full = 0, true
+	}
+}
+
+// query returns up to limit records of supi, or of all subscribers if supi
+// is empty, newest first. limit <= 0 means no limit.
+func (l *sqnAuditLog) query(supi string, limit int) []SqnAuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.records)
+	}
+	records := make([]SqnAuditRecord, 0)
+	for i := 1; i <= n && (limit <= 0 || len(records) < limit); i++ {
+		record := l.records[(l.next-i+len(l.records))%len(l.records)]
+		if supi == "" || record.Supi == supi {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (context *UDMContext) sqnAudit() *sqnAuditLog {
+	context.sqnAuditOnce.Do(func() {
+		size := factory.AdminDefaultSqnAuditSize
+		if factory.UdmConfig != nil {
+			size = factory.UdmConfig.GetAdmin().GetSqnAuditSize()
+		}
+		context.sqnAuditLog = &sqnAuditLog{records: make([]SqnAuditRecord, size)}
+	})
+	return context.sqnAuditLog
+}
+
+// AddSqnAuditRecord appends record to the SQN audit trail, replacing the
+// oldest record once the trail is full.
+func (context *UDMContext) AddSqnAuditRecord(record SqnAuditRecord) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	context.sqnAudit().add(record)
+}
+
+// SqnAuditRecords returns up to limit records of the SQN audit trail of supi,
+// or of all subscribers if supi is empty, newest first.
+func (context *UDMContext) SqnAuditRecords(supi string, limit int) []SqnAuditRecord {
+	return context.sqnAudit().query(supi, limit)
+}
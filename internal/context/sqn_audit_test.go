@@ -0,0 +1,27 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqnAuditLog(t *testing.T) {
+	l := &sqnAuditLog{records: make([]SqnAuditRecord, 3)}
+	require.Empty(t, l.query("", 0))
+
+	for _, supi := range []string{"imsi-1", "imsi-2", "imsi-1", "imsi-2", "imsi-1"} {
+		l.add(SqnAuditRecord{Supi: supi})
+	}
+
+	// the two oldest records were replaced
+	records := l.query("", 0)
+	require.Len(t, records, 3)
+	require.Equal(t, []string{"imsi-1", "imsi-2", "imsi-1"},
+		[]string{records[0].Supi, records[1].Supi, records[2].Supi})
+
+	require.Len(t, l.query("imsi-1", 0), 2)
+	require.Len(t, l.query("imsi-1", 1), 1)
+	require.Len(t, l.query("imsi-2", 0), 1)
+	require.Empty(t, l.query("imsi-3", 0))
+}
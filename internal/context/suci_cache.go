@@ -0,0 +1,96 @@
+package context
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/free5gc/udm/internal/logger"
+	ueau_metrics "github.com/free5gc/udm/internal/metrics/ueau"
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+// suciCache maps de-concealed SUCIs to their SUPI. All entries live for the
+// same ttl, so the insertion order is the expiry order and the oldest entry
+// is evicted once size is reached. Entries keep the SuciProfile the SUCI was
+// de-concealed with and only hit while it is the current one, so that SUCIs
+// of removed, replaced or expired keys stop resolving.
+type suciCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element // suci as key
+	order   *list.List               // of *suciCacheEntry, oldest first
+	now     func() time.Time
+}
+
+type suciCacheEntry struct {
+	suci    string
+	supi    string
+	profile suci.SuciProfile
+	expires time.Time
+}
+
+// newSuciCache returns nil, i.e. no cache, when cfg is nil
+func newSuciCache(cfg *factory.SuciCache) *suciCache {
+	if cfg == nil {
+		return nil
+	}
+	ttl, err := time.ParseDuration(cfg.GetTtl())
+	if err != nil {
+		logger.CtxLog.Errorf("SUCI cache disabled, invalid ttl: %+v", err)
+		return nil
+	}
+	return &suciCache{
+		size:    cfg.GetSize(),
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns the SUPI of suciStr if it was de-concealed with profile
+func (c *suciCache) get(suciStr string, profile suci.SuciProfile) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[suciStr]
+	if !ok {
+		ueau_metrics.IncrSuciCacheCounter(ueau_metrics.SuciCacheMiss)
+		return "", false
+	}
+	entry := element.Value.(*suciCacheEntry)
+	if c.now().After(entry.expires) || entry.profile != profile {
+		c.remove(element)
+		ueau_metrics.IncrSuciCacheCounter(ueau_metrics.SuciCacheMiss)
+		return "", false
+	}
+	ueau_metrics.IncrSuciCacheCounter(ueau_metrics.SuciCacheHit)
+	return entry.supi, true
+}
+
+func (c *suciCache) put(suciStr, supi string, profile suci.SuciProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if element, ok := c.entries[suciStr]; ok {
+		c.remove(element)
+	}
+	for front := c.order.Front(); front != nil; front = c.order.Front() {
+		if c.order.Len() < c.size && !now.After(front.Value.(*suciCacheEntry).expires) {
+			break
+		}
+		c.remove(front)
+	}
+	c.entries[suciStr] = c.order.PushBack(&suciCacheEntry{
+		suci:    suciStr,
+		supi:    supi,
+		profile: profile,
+		expires: now.Add(c.ttl),
+	})
+}
+
+func (c *suciCache) remove(element *list.Element) {
+	delete(c.entries, c.order.Remove(element).(*suciCacheEntry).suci)
+}
@@ -0,0 +1,58 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+func TestSuciCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := newSuciCache(&factory.SuciCache{Size: 2, Ttl: "1m"})
+	require.NotNil(t, c)
+	c.now = func() time.Time { return now }
+	profile := suci.SuciProfile{KeyId: 1, ProtectionScheme: suci.ProfileAScheme, PublicKey: "01"}
+
+	_, ok := c.get("suci-1", profile)
+	require.False(t, ok)
+
+	c.put("suci-1", "imsi-1", profile)
+	c.put("suci-2", "imsi-2", profile)
+	supi, ok := c.get("suci-1", profile)
+	require.True(t, ok)
+	require.Equal(t, "imsi-1", supi)
+
+	// the oldest entry is evicted once full
+	c.put("suci-3", "imsi-3", profile)
+	_, ok = c.get("suci-1", profile)
+	require.False(t, ok)
+	require.Len(t, c.entries, 2)
+
+	// put again renews the entry
+	now = now.Add(30 * time.Second)
+	c.put("suci-2", "imsi-2", profile)
+	now = now.Add(45 * time.Second)
+	_, ok = c.get("suci-3", profile)
+	require.False(t, ok)
+	supi, ok = c.get("suci-2", profile)
+	require.True(t, ok)
+	require.Equal(t, "imsi-2", supi)
+
+	// entries of another key are dropped
+	_, ok = c.get("suci-2", suci.SuciProfile{KeyId: 1, ProtectionScheme: suci.ProfileAScheme, PublicKey: "02"})
+	require.False(t, ok)
+	require.Len(t, c.entries, 0)
+	c.put("suci-5", "imsi-5", profile)
+
+	// expired entries are dropped
+	now = now.Add(time.Minute + time.Second)
+	c.put("suci-4", "imsi-4", profile)
+	require.Len(t, c.entries, 1)
+	require.Equal(t, c.order.Len(), len(c.entries))
+
+	require.Nil(t, newSuciCache(nil))
+}
@@ -22,10 +22,12 @@ var (
 	SdmLog      *logrus.Entry
 	PpLog       *logrus.Entry
 	EeLog       *logrus.Entry
+	UeidLog     *logrus.Entry
 	UtilLog     *logrus.Entry
 	SuciLog     *logrus.Entry
 	CallbackLog *logrus.Entry
 	ProcLog     *logrus.Entry
+	AdminLog    *logrus.Entry
 )
 
 func init() {
@@ -50,7 +52,9 @@ func init() {
 	SdmLog = NfLog.WithField(logger_util.FieldCategory, "SDM")
 	PpLog = NfLog.WithField(logger_util.FieldCategory, "PP")
 	EeLog = NfLog.WithField(logger_util.FieldCategory, "EE")
+	UeidLog = NfLog.WithField(logger_util.FieldCategory, "UEID")
 	UtilLog = NfLog.WithField(logger_util.FieldCategory, "Util")
 	SuciLog = NfLog.WithField(logger_util.FieldCategory, "Suci")
 	CallbackLog = NfLog.WithField(logger_util.FieldCategory, "Callback")
+	AdminLog = NfLog.WithField(logger_util.FieldCategory, "Admin")
 }
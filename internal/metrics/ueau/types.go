@@ -0,0 +1,63 @@
+package ueau
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	SUBSYSTEM_NAME = "ueau"
+)
+
+const (
+	VECTORS_GENERATED_COUNTER_NAME = "vectors_generated_total"
+	VECTORS_GENERATED_COUNTER_DESC = "Total number of authentication vectors generated by the UDM"
+
+	VECTOR_POOL_COUNTER_NAME = "vector_pool_requests_total"
+	VECTOR_POOL_COUNTER_DESC = "Total number of vector requests answered from or missing the vector pool"
+
+	VECTOR_POOL_GAUGE_NAME = "vector_pool_vectors"
+	VECTOR_POOL_GAUGE_DESC = "Number of authentication vectors held in the vector pool"
+
+	SQN_UPDATE_COUNTER_NAME = "sqn_updates_total"
+	SQN_UPDATE_COUNTER_DESC = "Total number of SQN updates sent to the UDR"
+
+	SQN_AUDIT_COUNTER_NAME = "sqn_audit_events_total"
+	SQN_AUDIT_COUNTER_DESC = "Total number of vector issuances and SQN re-synchronisations by outcome"
+
+	SUCI_CACHE_COUNTER_NAME = "suci_cache_requests_total"
+	SUCI_CACHE_COUNTER_DESC = "Total number of SUCI de-concealments answered from or missing the SUCI cache"
+)
+
+// Label names
+const (
+	RESULT_LABEL  = "result"
+	OUTCOME_LABEL = "outcome"
+)
+
+// Vector pool results
+const (
+	PoolHit     = "hit"
+	PoolMiss    = "miss"
+	PoolExpired = "expired"
+	PoolStale   = "stale"
+)
+
+// SUCI cache results
+const (
+	SuciCacheHit  = "hit"
+	SuciCacheMiss = "miss"
+)
+
+// SQN update results
+const (
+	SqnUpdateConflict = "conflict"
+)
+
+var (
+	VectorsGeneratedCounter prometheus.Counter
+	VectorPoolCounter       *prometheus.CounterVec
+	VectorPoolGauge         prometheus.Gauge
+	SqnUpdateCounter        *prometheus.CounterVec
+	SqnAuditCounter         *prometheus.CounterVec
+	SuciCacheCounter        *prometheus.CounterVec
+)
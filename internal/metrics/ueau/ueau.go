@@ -0,0 +1,128 @@
+// Package ueau holds the business metrics of the UE authentication service.
+package ueau
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/free5gc/util/metrics/utils"
+)
+
+func GetUeauMetrics(namespace string) []prometheus.Collector {
+	var metrics []prometheus.Collector
+
+	VectorsGeneratedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      VECTORS_GENERATED_COUNTER_NAME,
+			Help:      VECTORS_GENERATED_COUNTER_DESC,
+		},
+	)
+
+	metrics = append(metrics, VectorsGeneratedCounter)
+
+	VectorPoolCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      VECTOR_POOL_COUNTER_NAME,
+			Help:      VECTOR_POOL_COUNTER_DESC,
+		},
+		[]string{RESULT_LABEL},
+	)
+
+	metrics = append(metrics, VectorPoolCounter)
+
+	VectorPoolGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      VECTOR_POOL_GAUGE_NAME,
+			Help:      VECTOR_POOL_GAUGE_DESC,
+		},
+	)
+
+	metrics = append(metrics, VectorPoolGauge)
+
+	SqnUpdateCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      SQN_UPDATE_COUNTER_NAME,
+			Help:      SQN_UPDATE_COUNTER_DESC,
+		},
+		[]string{RESULT_LABEL},
+	)
+
+	metrics = append(metrics, SqnUpdateCounter)
+
+	SqnAuditCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      SQN_AUDIT_COUNTER_NAME,
+			Help:      SQN_AUDIT_COUNTER_DESC,
+		},
+		[]string{OUTCOME_LABEL},
+	)
+
+	metrics = append(metrics, SqnAuditCounter)
+
+	SuciCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      SUCI_CACHE_COUNTER_NAME,
+			Help:      SUCI_CACHE_COUNTER_DESC,
+		},
+		[]string{RESULT_LABEL},
+	)
+
+	metrics = append(metrics, SuciCacheCounter)
+
+	return metrics
+}
+
+func IncrVectorsGenerated(n int) {
+	if utils.IsBusinessMetricsEnabled() {
+		VectorsGeneratedCounter.Add(float64(n))
+	}
+}
+
+func IncrVectorPoolCounter(result string) {
+	if utils.IsBusinessMetricsEnabled() {
+		VectorPoolCounter.With(prometheus.Labels{
+			RESULT_LABEL: result,
+		}).Add(1)
+	}
+}
+
+func AddVectorPoolVectors(n int) {
+	if utils.IsBusinessMetricsEnabled() {
+		VectorPoolGauge.Add(float64(n))
+	}
+}
+
+func IncrSqnUpdateCounter(result string) {
+	if utils.IsBusinessMetricsEnabled() {
+		SqnUpdateCounter.With(prometheus.Labels{
+			RESULT_LABEL: result,
+		}).Add(1)
+	}
+}
+
+func IncrSqnAuditCounter(outcome string) {
+	if utils.IsBusinessMetricsEnabled() {
+		SqnAuditCounter.With(prometheus.Labels{
+			OUTCOME_LABEL: outcome,
+		}).Add(1)
+	}
+}
+
+func IncrSuciCacheCounter(result string) {
+	if utils.IsBusinessMetricsEnabled() {
+		SuciCacheCounter.With(prometheus.Labels{
+			RESULT_LABEL: result,
+		}).Add(1)
+	}
+}
@@ -0,0 +1,87 @@
+package sbi
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+func (s *Server) getAdminRoutes() []Route {
+	return []Route{
+		{
+			"GetSqnAudit",
+			http.MethodGet,
+			"/sqn-audit",
+			s.HandleGetSqnAudit,
+		},
+
+		{
+			"GetAuthLockouts",
+			http.MethodGet,
+			"/auth-lockouts",
+			s.HandleGetAuthLockouts,
+		},
+
+		{
+			"DeleteAuthLockout",
+			http.MethodDelete,
+			"/auth-lockouts/:supi",
+			s.HandleDeleteAuthLockout,
+		},
+	}
+}
+
+// adminAuthorizationCheck requires token as bearer token. Without token
+// every request is rejected.
+func adminAuthorizationCheck(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bearer, ok := strings.CutPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			logger.AdminLog.Warnf("Unauthorized admin request from [%s]", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ProblemDetails{
+				Title:  "Unauthorized",
+				Status: http.StatusUnauthorized,
+			})
+			return
+		}
+	}
+}
+
+// HandleGetSqnAudit - Query the SQN audit trail, newest records first
+func (s *Server) HandleGetSqnAudit(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			problemDetail := models.ProblemDetails{
+				Title:  "Malformed request syntax",
+				Status: http.StatusBadRequest,
+				Detail: "[Query Parameter] limit is invalid",
+				Cause:  "INVALID_QUERY_PARAM",
+			}
+			logger.AdminLog.Errorln(problemDetail.Detail)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+			c.JSON(http.StatusBadRequest, problemDetail)
+			return
+		}
+	}
+
+	s.Processor().GetSqnAuditProcedure(c, c.Query("supi"), limit)
+}
+
+// HandleGetAuthLockouts - List the subscribers locked out of vector generation
+func (s *Server) HandleGetAuthLockouts(c *gin.Context) {
+	s.Processor().GetAuthLockoutsProcedure(c)
+}
+
+// HandleDeleteAuthLockout - Lift the lockout of a subscriber
+func (s *Server) HandleDeleteAuthLockout(c *gin.Context) {
+	s.Processor().DeleteAuthLockoutProcedure(c, c.Param("supi"))
+}
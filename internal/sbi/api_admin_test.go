@@ -0,0 +1,103 @@
+package sbi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/processor"
+	"github.com/free5gc/udm/pkg/factory"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+type testServerUdm struct {
+	*mockapp.MockApp
+	processor *processor.Processor
+}
+
+func (u *testServerUdm) Processor() *processor.Processor {
+	return u.processor
+}
+
+func (u *testServerUdm) CancelContext() context.Context {
+	return context.Background()
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testProcessor, err := processor.NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	cfg := &factory.Config{
+		Configuration: &factory.Configuration{
+			Admin: &factory.Admin{Enable: true, Token: "secret"},
+		},
+	}
+	mockApp.EXPECT().Config().Return(cfg).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+	router := newRouter(&Server{ServerUdm: &testServerUdm{MockApp: mockApp, processor: testProcessor}})
+
+	testCases := []struct {
+		name          string
+		authorization string
+		expectStatus  int
+	}{
+		{
+			name:         "No token",
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "Wrong token",
+			authorization: "Bearer guess",
+			expectStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Not a bearer token",
+			authorization: "secret",
+			expectStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Token",
+			authorization: "Bearer secret",
+			expectStatus:  http.StatusNotFound, // not locked out
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, req := range []*http.Request{
+				httptest.NewRequest(http.MethodDelete, factory.UdmAdminResUriPrefix+"/auth-lockouts/imsi-1", nil),
+				httptest.NewRequest(http.MethodGet, factory.UdmAdminResUriPrefix+"/auth-lockouts", nil),
+				httptest.NewRequest(http.MethodGet, factory.UdmAdminResUriPrefix+"/sqn-audit", nil),
+			} {
+				if tc.authorization != "" {
+					req.Header.Set("Authorization", tc.authorization)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if tc.expectStatus == http.StatusUnauthorized || req.Method == http.MethodDelete {
+					require.Equal(t, tc.expectStatus, w.Code, req.URL.Path)
+				} else {
+					require.Equal(t, http.StatusOK, w.Code, req.URL.Path)
+				}
+			}
+		})
+	}
+
+	// without a token the admin API is closed
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, factory.UdmAdminResUriPrefix+"/sqn-audit", nil)
+	c.Request.Header.Set("Authorization", "Bearer ")
+	adminAuthorizationCheck("")(c)
+	require.True(t, c.IsAborted())
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
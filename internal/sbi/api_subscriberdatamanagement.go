@@ -101,12 +101,44 @@ func (s *Server) HandleGetSmfSelectData(c *gin.Context) {
 
 // GetSmsMngData - retrieve a UE's SMS Management Subscription Data
 func (s *Server) HandleGetSmsMngData(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{})
+	query := url.Values{}
+	query.Set("plmn-id", c.Query("plmn-id"))
+	query.Set("supported-features", c.Query("supported-features"))
+
+	logger.SdmLog.Infof("Handle GetSmsMngData")
+
+	supi := c.Params.ByName("supi")
+	plmnIDStruct, problemDetails := s.getPlmnIDStruct(query)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	plmnID := plmnIDStruct.Mcc + plmnIDStruct.Mnc
+	supportedFeatures := query.Get("supported-features")
+
+	s.Processor().GetSmsMngDataProcedure(c, supi, plmnID, supportedFeatures)
 }
 
 // GetSmsData - retrieve a UE's SMS Subscription Data
 func (s *Server) HandleGetSmsData(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{})
+	query := url.Values{}
+	query.Set("plmn-id", c.Query("plmn-id"))
+	query.Set("supported-features", c.Query("supported-features"))
+
+	logger.SdmLog.Infof("Handle GetSmsData")
+
+	supi := c.Params.ByName("supi")
+	plmnIDStruct, problemDetails := s.getPlmnIDStruct(query)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	plmnID := plmnIDStruct.Mcc + plmnIDStruct.Mnc
+	supportedFeatures := query.Get("supported-features")
+
+	s.Processor().GetSmsDataProcedure(c, supi, plmnID, supportedFeatures)
 }
 
 // GetSupi - retrieve multiple data sets
@@ -1,7 +1,9 @@
 package sbi
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -19,6 +21,13 @@ func (s *Server) getUEAuthenticationRoutes() []Route {
 			"/",
 			s.HandleIndex,
 		},
+
+		{
+			"GenerateProseAV",
+			http.MethodPost,
+			"/:supi/prose-security-information/generate-av",
+			s.HandleGenerateProseAV,
+		},
 	}
 }
 
@@ -99,24 +108,191 @@ func (s *Server) HandleGenerateAuthData(c *gin.Context) {
 	s.Processor().GenerateAuthDataProcedure(c, authInfoReq, supiOrSuci)
 }
 
+// DeleteAuth - Deletes the authentication result in the UDM
 func (s *Server) HandleDeleteAuth(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	// TS 29.503 removes the result with a PUT of the AuthEvent having authRemovalInd set
+	if c.Request.Method == http.MethodPut {
+		var authEvent models.AuthEvent
+		requestBody, err := c.GetRawData()
+		if err != nil {
+			problemDetail := models.ProblemDetails{
+				Title:  "System failure",
+				Status: http.StatusInternalServerError,
+				Detail: err.Error(),
+				Cause:  "SYSTEM_FAILURE",
+			}
+			logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+			c.JSON(http.StatusInternalServerError, problemDetail)
+			return
+		}
+
+		err = openapi.Deserialize(&authEvent, requestBody, "application/json")
+		if err == nil && !authEvent.AuthRemovalInd {
+			err = fmt.Errorf("authRemovalInd is not set")
+		}
+		if err != nil {
+			problemDetail := "[Request Body] " + err.Error()
+			rsp := models.ProblemDetails{
+				Title:  "Malformed request syntax",
+				Status: http.StatusBadRequest,
+				Detail: problemDetail,
+			}
+			logger.UeauLog.Errorln(problemDetail)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+			c.JSON(int(rsp.Status), rsp)
+			return
+		}
+	}
+
+	logger.UeauLog.Infoln("Handle DeleteAuthRequest")
+
+	s.Processor().DeleteAuthProcedure(c, c.Param("supi"), c.Param("thirdLayer"))
 }
 
+// GenerateAv - Generate authentication vectors for the HSS
 func (s *Server) HandleGenerateAv(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	if c.Request.Method != http.MethodPost {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	var authInfoReq models.HssAuthenticationInfoRequest
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&authInfoReq, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GenerateAvRequest")
+
+	supi := c.Param("supi")
+	hssAuthType := c.Param("hssAuthType")
+
+	s.Processor().GenerateAvProcedure(c, authInfoReq, supi, hssAuthType)
 }
 
+// GenerateGbaAv - Generate a 3G AKA authentication vector for GBA
 func (s *Server) HandleGenerateGbaAv(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var authInfoReq models.GbaAuthenticationInfoRequest
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&authInfoReq, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GenerateGbaAvRequest")
+
+	supi := c.Param("supi")
+
+	s.Processor().GenerateGbaAvProcedure(c, authInfoReq, supi)
 }
 
+// GenerateProseAV - Generate authentication data for a 5G ProSe Remote UE
 func (s *Server) HandleGenerateProseAV(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var authInfoReq models.ProSeAuthenticationInfoRequest
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&authInfoReq, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GenerateProseAVRequest")
+
+	// the route shares the :supi wildcard of the UEAU layer paths
+	supiOrSuci := c.Param("supi")
+
+	s.Processor().GenerateProseAvProcedure(c, authInfoReq, supiOrSuci)
 }
 
+// GetRgAuthData - Authentication data for the FN-RG
 func (s *Server) HandleGetRgAuthData(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	authenticatedInd, err := strconv.ParseBool(c.Query("authenticated-ind"))
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "[Query Parameter] authenticated-ind is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Errorln(problemDetail.Detail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusBadRequest, problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GetRgAuthDataRequest")
+
+	s.Processor().GetRgAuthDataProcedure(c, c.Param("supiOrSuci"), authenticatedInd)
 }
 
 func (s *Server) UEAUTwoLayerPathHandlerFunc(c *gin.Context) {
@@ -128,8 +304,9 @@ func (s *Server) UEAUTwoLayerPathHandlerFunc(c *gin.Context) {
 		return
 	}
 
-	// for "/:supiOrSuci/security-information-rg"
-	if twoLayer == "security-information-rg" && http.MethodGet == c.Request.Method {
+	// for "/:supiOrSuci/security-information-rg", also served as rg-authentication-data
+	if (twoLayer == "security-information-rg" || twoLayer == "rg-authentication-data") &&
+		http.MethodGet == c.Request.Method {
 		var tmpParams gin.Params
 		tmpParams = append(tmpParams, gin.Param{Key: "supiOrSuci", Value: c.Param("supi")})
 		c.Params = tmpParams
@@ -144,26 +321,19 @@ func (s *Server) UEAUThreeLayerPathHandlerFunc(c *gin.Context) {
 	twoLayer := c.Param("twoLayer")
 
 	// for "/:supi/auth-events/:authEventId"
-	if twoLayer == "auth-events" && http.MethodPut == c.Request.Method {
+	if twoLayer == "auth-events" &&
+		(http.MethodDelete == c.Request.Method || http.MethodPut == c.Request.Method) {
 		s.HandleDeleteAuth(c)
 		return
 	}
 
 	// for "/:supi/gba-security-information/generate-av"
-	if twoLayer == "gba-security-information" && http.MethodPost == c.Request.Method {
+	if twoLayer == "gba-security-information" && c.Param("thirdLayer") == "generate-av" &&
+		http.MethodPost == c.Request.Method {
 		s.HandleGenerateGbaAv(c)
 		return
 	}
 
-	// for "/:supiOrSuci/prose-security-information/generate-av"
-	if twoLayer == "prose-security-information" && http.MethodPost == c.Request.Method {
-		var tmpParams gin.Params
-		tmpParams = append(tmpParams, gin.Param{Key: "supiOrSuci", Value: c.Param("supi")})
-		c.Params = tmpParams
-		s.HandleGenerateProseAV(c)
-		return
-	}
-
 	// for "/:supiOrSuci/security-information/generate-auth-data"
 	if twoLayer == "security-information" && http.MethodPost == c.Request.Method {
 		var tmpParams gin.Params
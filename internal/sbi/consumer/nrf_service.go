@@ -202,6 +202,7 @@ func (s *nnrfService) buildNfProfile(udmContext *udm_context.UDMContext) (
 		profile.NfServices = append(profile.NfServices, nfService)
 	}
 	profile.UdmInfo = &models.UdmInfo{
+		RoutingIndicators: udmContext.RoutingIndicators,
 		// Todo
 		// SupiRanges: &[]models.SupiRange{
 		// 	{
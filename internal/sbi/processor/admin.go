@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+// GetSqnAuditProcedure returns up to limit records of the SQN audit trail of
+// supi, or of all subscribers if supi is empty.
+func (p *Processor) GetSqnAuditProcedure(c *gin.Context, supi string, limit int) {
+	c.JSON(http.StatusOK, p.Context().SqnAuditRecords(supi, limit))
+}
+
+// GetAuthLockoutsProcedure lists the subscribers locked out of vector
+// generation
+func (p *Processor) GetAuthLockoutsProcedure(c *gin.Context) {
+	if p.authLockout == nil {
+		c.JSON(http.StatusOK, []AuthLockoutStatus{})
+		return
+	}
+	c.JSON(http.StatusOK, p.authLockout.list())
+}
+
+// DeleteAuthLockoutProcedure lifts the lockout of supi
+func (p *Processor) DeleteAuthLockoutProcedure(c *gin.Context, supi string) {
+	if p.authLockout == nil || !p.authLockout.clear(supi) {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "DATA_NOT_FOUND",
+			Detail: fmt.Sprintf("[%s] is not locked out", supi),
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	logger.AdminLog.Infof("Lockout of [%s] cleared", supi)
+	c.Status(http.StatusNoContent)
+}
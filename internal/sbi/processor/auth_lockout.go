@@ -0,0 +1,173 @@
+package processor
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+// authLockout counts the authentication failures of each subscriber and
+// locks the subscriber out of vector generation once too many of them
+// happened within the window.
+type authLockout struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	duration    time.Duration
+	subscribers map[string]*authFailures // supi as key
+	nextSweep   time.Time
+	now         func() time.Time
+}
+
+type authFailures struct {
+	count       int
+	since       time.Time
+	lockedUntil time.Time
+}
+
+// AuthLockoutStatus is a subscriber locked out of vector generation
+type AuthLockoutStatus struct {
+	Supi        string    `json:"supi"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// newAuthLockout returns nil, i.e. no lockout, when cfg is nil
+func newAuthLockout(cfg *factory.AuthLockout) *authLockout {
+	if cfg == nil {
+		return nil
+	}
+	window, err := time.ParseDuration(cfg.GetWindow())
+	if err != nil {
+		logger.UeauLog.Errorf("Authentication lockout disabled, invalid window: %+v", err)
+		return nil
+	}
+	duration, err := time.ParseDuration(cfg.GetDuration())
+	if err != nil {
+		logger.UeauLog.Errorf("Authentication lockout disabled, invalid duration: %+v", err)
+		return nil
+	}
+	return &authLockout{
+		maxFailures: cfg.GetMaxFailures(),
+		window:      window,
+		duration:    duration,
+		subscribers: make(map[string]*authFailures),
+		now:         time.Now,
+	}
+}
+
+// fail counts an authentication failure of supi
+func (l *authLockout) fail(supi string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.After(l.nextSweep) {
+		l.sweep(now)
+		l.nextSweep = now.Add(l.window)
+	}
+
+	failures, ok := l.subscribers[supi]
+	if !ok || (now.After(failures.lockedUntil) && now.Sub(failures.since) > l.window) {
+		failures = &authFailures{since: now}
+		l.subscribers[supi] = failures
+	}
+	failures.count++
+	if failures.count >= l.maxFailures && now.After(failures.lockedUntil) {
+		failures.lockedUntil = now.Add(l.duration)
+		logger.UeauLog.Warnf("Lock out [%s] until %s after %d authentication failures",
+			supi, failures.lockedUntil.Format(time.RFC3339), failures.count)
+	}
+}
+
+// succeed forgets the failures of supi unless it is locked out
+func (l *authLockout) succeed(supi string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if failures, ok := l.subscribers[supi]; ok && l.now().After(failures.lockedUntil) {
+		delete(l.subscribers, supi)
+	}
+}
+
+// lockedUntil returns the end of the lockout of supi, if locked out
+func (l *authLockout) lockedUntil(supi string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	failures, ok := l.subscribers[supi]
+	if !ok || !l.now().Before(failures.lockedUntil) {
+		return time.Time{}, false
+	}
+	return failures.lockedUntil, true
+}
+
+// list returns the subscribers locked out, ordered by SUPI
+func (l *authLockout) list() []AuthLockoutStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	lockouts := make([]AuthLockoutStatus, 0)
+	for supi, failures := range l.subscribers {
+		if now.Before(failures.lockedUntil) {
+			lockouts = append(lockouts, AuthLockoutStatus{
+				Supi:        supi,
+				Failures:    failures.count,
+				LockedUntil: failures.lockedUntil,
+			})
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].Supi < lockouts[j].Supi })
+	return lockouts
+}
+
+// clear lifts the lockout of supi and forgets its failures
+func (l *authLockout) clear(supi string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	failures, ok := l.subscribers[supi]
+	if !ok {
+		return false
+	}
+	delete(l.subscribers, supi)
+	return l.now().Before(failures.lockedUntil)
+}
+
+func (l *authLockout) sweep(now time.Time) {
+	for supi, failures := range l.subscribers {
+		if now.After(failures.lockedUntil) && now.Sub(failures.since) > l.window {
+			delete(l.subscribers, supi)
+		}
+	}
+}
+
+// checkAuthLockout rejects the vector generation for a locked out supi. On
+// failure the error response has been written.
+func (p *Processor) checkAuthLockout(c *gin.Context, supi string) bool {
+	if p.authLockout == nil {
+		return true
+	}
+	lockedUntil, locked := p.authLockout.lockedUntil(supi)
+	if !locked {
+		return true
+	}
+
+	problemDetails := &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  authenticationRejected,
+		Detail: fmt.Sprintf("too many authentication failures, locked until %s", lockedUntil.Format(time.RFC3339)),
+	}
+
+	logger.UeauLog.Warnf("Reject vector generation for locked out [%s]", supi)
+	c.Header("Retry-After", strconv.Itoa(int(lockedUntil.Sub(p.authLockout.now()).Seconds())+1))
+	c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+	c.JSON(int(problemDetails.Status), problemDetails)
+	return false
+}
@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	"github.com/free5gc/udm/pkg/factory"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+func TestAuthLockout(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newAuthLockout(&factory.AuthLockout{MaxFailures: 3, Window: "1m", Duration: "10m"})
+	require.NotNil(t, l)
+	l.now = func() time.Time { return now }
+
+	// failures outside the window are forgotten
+	l.fail("imsi-1")
+	l.fail("imsi-1")
+	now = now.Add(2 * time.Minute)
+	l.fail("imsi-1")
+	_, locked := l.lockedUntil("imsi-1")
+	require.False(t, locked)
+
+	// a successful authentication resets the count
+	l.succeed("imsi-1")
+	l.fail("imsi-1")
+	l.fail("imsi-1")
+	_, locked = l.lockedUntil("imsi-1")
+	require.False(t, locked)
+
+	l.fail("imsi-1")
+	lockedUntil, locked := l.lockedUntil("imsi-1")
+	require.True(t, locked)
+	require.Equal(t, now.Add(10*time.Minute), lockedUntil)
+	l.succeed("imsi-1")
+	_, locked = l.lockedUntil("imsi-1")
+	require.True(t, locked)
+
+	for i := 0; i < 3; i++ {
+		l.fail("imsi-2")
+	}
+	require.Equal(t, []AuthLockoutStatus{
+		{Supi: "imsi-1", Failures: 3, LockedUntil: lockedUntil},
+		{Supi: "imsi-2", Failures: 3, LockedUntil: lockedUntil},
+	}, l.list())
+
+	require.True(t, l.clear("imsi-2"))
+	require.False(t, l.clear("imsi-2"))
+	_, locked = l.lockedUntil("imsi-2")
+	require.False(t, locked)
+
+	now = now.Add(11 * time.Minute)
+	_, locked = l.lockedUntil("imsi-1")
+	require.False(t, locked)
+	require.Empty(t, l.list())
+
+	require.Nil(t, newAuthLockout(nil))
+}
+
+func TestGenerateAuthDataProcedureAuthLockout(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+	testProcessor.authLockout = newAuthLockout(&factory.AuthLockout{MaxFailures: 2})
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000013"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	for i := 0; i < 2; i++ {
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Put("/subscription-data/imsi-208930000000013/authentication-data/authentication-status").
+			Reply(204)
+
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.ConfirmAuthDataProcedure(c, models.AuthEvent{
+			NfInstanceId:       "5a3f6c1e-8d2b-4c7a-9e0f-1b2c3d4e5f60",
+			Success:            false,
+			AuthType:           models.UdmUeauAuthType__5_G_AKA,
+			ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		}, ue.Supi)
+		require.Equal(t, 201, httpRecorder.Code)
+	}
+
+	// no UDR request for a locked out subscriber
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateAuthDataProcedure(c, models.AuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+	}, ue.Supi)
+	require.Equal(t, 403, httpRecorder.Code)
+	require.NotEmpty(t, httpRecorder.Header().Get("Retry-After"))
+	require.True(t, gock.IsDone())
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.GetAuthLockoutsProcedure(c)
+	require.Equal(t, 200, httpRecorder.Code)
+	require.Contains(t, httpRecorder.Body.String(), ue.Supi)
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeleteAuthLockoutProcedure(c, ue.Supi)
+	require.Equal(t, 204, c.Writer.Status())
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeleteAuthLockoutProcedure(c, ue.Supi)
+	require.Equal(t, 404, httpRecorder.Code)
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+// SQN IND key of the vectors handed out to the BSF
+const gbaIndKey = "gba"
+
+// GenerateGbaAvProcedure returns a 3G AKA vector for GBA bootstrapping, TS 33.220
+// clause 4.5.2, resynchronising SQN when the BSF sends AUTS.
+func (p *Processor) GenerateGbaAvProcedure(
+	c *gin.Context,
+	authInfoRequest models.GbaAuthenticationInfoRequest,
+	supi string,
+) {
+	if authInfoRequest.AuthType != models.GbaAuthType_DIGEST_AKAV1_MD5 {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: fmt.Sprintf("unsupported GBA authType [%s]", authInfoRequest.AuthType),
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	supi, err = p.Context().SuciToSupi(supi)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if !p.checkAuthLockout(c, supi) {
+		return
+	}
+
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubs, ok := p.queryAuthSubscription(ctx, c, supi)
+	if !ok {
+		return
+	}
+	vectors, ok := p.generateAuthVectors(ctx, c, supi, authSubs, gbaIndKey, 1,
+		authInfoRequest.ResynchronizationInfo, &udm_context.SqnAuditRecord{})
+	if !ok {
+		return
+	}
+
+	response := &models.GbaAuthenticationInfoResult{
+		Var3gAkaAv: &models.Model3GAkaAv{
+			Rand: hex.EncodeToString(vectors[0].Rand),
+			Xres: hex.EncodeToString(vectors[0].Xres),
+			Autn: hex.EncodeToString(vectors[0].Autn),
+			Ck:   hex.EncodeToString(vectors[0].Ck),
+			Ik:   hex.EncodeToString(vectors[0].Ik),
+		},
+	}
+	c.JSON(http.StatusOK, response)
+}
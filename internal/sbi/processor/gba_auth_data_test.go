@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+	"github.com/free5gc/util/milenage"
+)
+
+func TestGenerateGbaAvProcedure(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000005"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	k, _ := hex.DecodeString("465b5ce8b199b49faa5f0a2ee238a6bc")
+	opc, _ := hex.DecodeString("cd63cb71954a9f4e48a5994e37a02baf")
+	rand, _ := hex.DecodeString("23553cbe9637a89d218ae64dae47bf35")
+	sqnMs, _ := hex.DecodeString("000000000100")
+	auts, err := milenage.GenerateAUTS(opc, k, rand, sqnMs)
+	require.NoError(t, err)
+	badAuts := append([]byte{}, auts...)
+	badAuts[len(badAuts)-1] ^= 0x01
+
+	testCases := []struct {
+		name         string
+		request      models.GbaAuthenticationInfoRequest
+		expectStatus int
+	}{
+		{
+			name:         "3G AKA vector",
+			request:      models.GbaAuthenticationInfoRequest{AuthType: models.GbaAuthType_DIGEST_AKAV1_MD5},
+			expectStatus: 200,
+		},
+		{
+			name: "Resynchronisation",
+			request: models.GbaAuthenticationInfoRequest{
+				AuthType: models.GbaAuthType_DIGEST_AKAV1_MD5,
+				ResynchronizationInfo: &models.ResynchronizationInfo{
+					Rand: hex.EncodeToString(rand),
+					Auts: hex.EncodeToString(auts),
+				},
+			},
+			expectStatus: 200,
+		},
+		{
+			name: "Resynchronisation with wrong MAC-S",
+			request: models.GbaAuthenticationInfoRequest{
+				AuthType: models.GbaAuthType_DIGEST_AKAV1_MD5,
+				ResynchronizationInfo: &models.ResynchronizationInfo{
+					Rand: hex.EncodeToString(rand),
+					Auts: hex.EncodeToString(badAuts),
+				},
+			},
+			expectStatus: 403,
+		},
+		{
+			name:         "Unsupported auth type",
+			request:      models.GbaAuthenticationInfoRequest{AuthType: "DIGEST_MD5"},
+			expectStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectStatus != 400 {
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Get("/subscription-data/imsi-208930000000005/authentication-data/authentication-subscription").
+					Reply(200).
+					AddHeader("Content-Type", "application/json").
+					JSON(models.AuthenticationSubscription{
+						EncPermanentKey:               hex.EncodeToString(k),
+						SequenceNumber:                &models.SequenceNumber{Sqn: "000000000023"},
+						AuthenticationManagementField: "8000",
+						EncOpcKey:                     hex.EncodeToString(opc),
+					})
+			}
+			if tc.expectStatus == 200 {
+				gock.New("http://127.0.0.4:8000").
+					Patch("/nudr-dr/v2/subscription-data/imsi-208930000000005/authentication-data/authentication-subscription").
+					Reply(204).
+					JSON(map[string]string{})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateGbaAvProcedure(c, tc.request, ue.Supi)
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectStatus != 200 {
+				return
+			}
+			var res models.GbaAuthenticationInfoResult
+			require.NoError(t, openapi.Deserialize(&res, httpRecorder.Body.Bytes(), "application/json"))
+			require.NotNil(t, res.Var3gAkaAv)
+			require.Len(t, res.Var3gAkaAv.Rand, 32)
+			require.Len(t, res.Var3gAkaAv.Autn, 32)
+			require.Len(t, res.Var3gAkaAv.Xres, 16)
+			require.Len(t, res.Var3gAkaAv.Ck, 32)
+			require.Len(t, res.Var3gAkaAv.Ik, 32)
+		})
+	}
+}
@@ -1,13 +1,17 @@
 package processor
 
 import (
+	"context"
 	cryptoRand "crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"net/http"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,29 +20,35 @@ import (
 	"github.com/free5gc/openapi"
 	"github.com/free5gc/openapi/models"
 	Nudr_DataRepository "github.com/free5gc/openapi/udr/DataRepository"
+	udm_context "github.com/free5gc/udm/internal/context"
 	"github.com/free5gc/udm/internal/logger"
+	ueau_metrics "github.com/free5gc/udm/internal/metrics/ueau"
 	"github.com/free5gc/udm/internal/util"
-	"github.com/free5gc/udm/pkg/suci"
 	"github.com/free5gc/util/metrics/sbi"
+	metrics_utils "github.com/free5gc/util/metrics/utils"
 	"github.com/free5gc/util/ueauth"
 )
 
 const (
-	SqnMAx    int64 = 0xFFFFFFFFFFFF
 	ind       int64 = 32
 	keyStrLen int   = 32
 	opStrLen  int   = 32
 	opcStrLen int   = 32
 )
 
+// maxSqnUpdateAttempts bounds the retries of the conditional SQN update
+const maxSqnUpdateAttempts = 3
+
+var errInvalidSqn = errors.New("invalid SQN")
+
 const (
 	authenticationRejected string = "AUTHENTICATION_REJECTED"
 	resyncAMF              string = "0000"
 )
 
-func (p *Processor) aucSQN(opc, k, auts, rand []byte) ([]byte, []byte) {
+func (p *Processor) aucSQN(alg util.AuthAlgorithm, opc, k, auts, rand []byte) ([]byte, []byte) {
 	AK, SQNms := make([]byte, 6), make([]byte, 6)
-	macS := make([]byte, 8)
+	macS := make([]byte, alg.Params().MacLength)
 	ConcSQNms := auts[:6]
 	AMF, err := hex.DecodeString(resyncAMF)
 	if err != nil {
@@ -47,9 +57,10 @@ func (p *Processor) aucSQN(opc, k, auts, rand []byte) ([]byte, []byte) {
 
 	logger.UeauLog.Tracef("aucSQN: ConcSQNms=[%x]", ConcSQNms)
 
-	err = util.MilenageF2345(opc, k, rand, nil, nil, nil, nil, AK)
+	// SQNms is concealed with AK* (f5*)
+	err = alg.F5Star(opc, k, rand, AK)
 	if err != nil {
-		logger.UeauLog.Errorln("aucSQN milenage F2345 err:", err)
+		logger.UeauLog.Errorln("aucSQN F5* err:", err)
 	}
 
 	for i := 0; i < 6; i++ {
@@ -58,14 +69,104 @@ func (p *Processor) aucSQN(opc, k, auts, rand []byte) ([]byte, []byte) {
 
 	logger.UeauLog.Tracef("aucSQN: opc=[%x], k=[%x], rand=[%x], AMF=[%x], SQNms=[%x]\n", opc, k, rand, AMF, SQNms)
 	// The AMF used to calculate MAC-S assumes a dummy value of all zeros
-	err = util.MilenageF1(opc, k, rand, SQNms, AMF, nil, macS)
+	err = alg.F1Star(opc, k, rand, SQNms, AMF, macS)
 	if err != nil {
-		logger.UeauLog.Errorln("aucSQN milenage F1 err:", err)
+		logger.UeauLog.Errorln("aucSQN F1* err:", err)
 	}
 	logger.UeauLog.Tracef("aucSQN: macS=[%x]\n", macS)
 	return SQNms, macS
 }
 
+// deriveXresStarKausf derives XRES* and KAUSF for 5G AKA, TS 33.501 Annex A.2 and A.4.
+func deriveXresStarKausf(ck, ik []byte, servingNetworkName string, rand, res, sqnXorAk []byte) (
+	xresStar, kausf []byte, err error,
+) {
+	key := append(append([]byte{}, ck...), ik...)
+	P0 := []byte(servingNetworkName)
+
+	kdfValForXresStar, err := ueauth.GetKDFValue(key, ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION,
+		P0, ueauth.KDFLen(P0), rand, ueauth.KDFLen(rand), res, ueauth.KDFLen(res))
+	if err != nil {
+		return nil, nil, err
+	}
+	kausf, err = ueauth.GetKDFValue(key, ueauth.FC_FOR_KAUSF_DERIVATION,
+		P0, ueauth.KDFLen(P0), sqnXorAk, ueauth.KDFLen(sqnXorAk))
+	if err != nil {
+		return nil, nil, err
+	}
+	return kdfValForXresStar[len(kdfValForXresStar)/2:], kausf, nil
+}
+
+// deriveCkPrimeIkPrime derives CK' and IK' for EAP-AKA', RFC 5448 clause 3.3
+// and TS 33.501 Annex A.3 where the serving network name is the network name.
+func deriveCkPrimeIkPrime(ck, ik []byte, networkName string, sqnXorAk []byte) (ckPrime, ikPrime []byte, err error) {
+	key := append(append([]byte{}, ck...), ik...)
+	P0 := []byte(networkName)
+
+	kdfVal, err := ueauth.GetKDFValue(key, ueauth.FC_FOR_CK_PRIME_IK_PRIME_DERIVATION,
+		P0, ueauth.KDFLen(P0), sqnXorAk, ueauth.KDFLen(sqnXorAk))
+	if err != nil {
+		return nil, nil, err
+	}
+	return kdfVal[:len(kdfVal)/2], kdfVal[len(kdfVal)/2:], nil
+}
+
+// decryptAuthSubscriptionKeys replaces K, OPc and TOPc of authSubs with their
+// plaintext using the KEK referenced by ProtectionParameterId. With KEKs
+// configured, keys without ProtectionParameterId are rejected unless plaintext
+// keys are explicitly allowed.
+func (p *Processor) decryptAuthSubscriptionKeys(supi string, authSubs *models.AuthenticationSubscription) error {
+	if !util.KeyEncryptionEnabled() {
+		return nil
+	}
+	if authSubs.ProtectionParameterId == "" {
+		if !util.PlaintextKeysAllowed() {
+			return fmt.Errorf("subscriber keys are not encrypted, protectionParameterId is missing")
+		}
+		logger.UeauLog.Warnln("Subscriber keys without protectionParameterId accepted as plaintext")
+		return nil
+	}
+
+	for _, encKey := range []struct {
+		field string
+		value *string
+	}{
+		{"encPermanentKey", &authSubs.EncPermanentKey},
+		{"encOpcKey", &authSubs.EncOpcKey},
+		{"encTopcKey", &authSubs.EncTopcKey},
+	} {
+		if *encKey.value == "" {
+			continue
+		}
+		key, err := util.DecryptSubscriberKey(authSubs.ProtectionParameterId, *encKey.value,
+			util.SubscriberKeyAad(supi, encKey.field))
+		if err != nil {
+			return fmt.Errorf("decrypt %s with protectionParameterId [%s]: %w",
+				encKey.field, authSubs.ProtectionParameterId, err)
+		}
+		*encKey.value = key
+	}
+	return nil
+}
+
+func (p *Processor) deriveOpc(alg util.AuthAlgorithm, supi string, k, op []byte) ([]byte, error) {
+	ue, ok := p.Context().UdmUeFindBySupi(supi)
+	if ok {
+		if opc, cached := ue.GetDerivedOpc(k, op); cached {
+			return opc, nil
+		}
+	}
+
+	opc, err := alg.DeriveOpc(k, op)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		ue.SetDerivedOpc(k, op, opc)
+	}
+	return opc, nil
+}
+
 func (p *Processor) strictHex(ss string, n int) string {
 	l := len(ss)
 	if l < n {
@@ -97,6 +198,13 @@ func (p *Processor) ConfirmAuthDataProcedure(c *gin.Context,
 		return
 	}
 
+	ue, ok := p.Context().UdmUeFindBySupi(supi)
+	if !ok {
+		ue = p.Context().NewUdmUe(supi)
+	}
+	ue.AuthEventLock.Lock()
+	defer ue.AuthEventLock.Unlock()
+
 	_, err = client.AuthenticationStatusDocumentApi.CreateAuthenticationStatus(
 		ctx, &createAuthStatusRequest)
 	if err != nil {
@@ -113,8 +221,93 @@ func (p *Processor) ConfirmAuthDataProcedure(c *gin.Context,
 		return
 	}
 
-	// AuthEvent in response body is optional
-	c.JSON(http.StatusCreated, gin.H{})
+	if p.authLockout != nil {
+		if authEvent.Success {
+			p.authLockout.succeed(supi)
+		} else {
+			p.authLockout.fail(supi)
+		}
+	}
+
+	c.Header("Location", ue.GetAuthEventLocationURI(udm_context.AuthEventId(supi, &authEvent)))
+	c.JSON(http.StatusCreated, authEvent)
+}
+
+func (p *Processor) DeleteAuthProcedure(c *gin.Context, supi string, authEventId string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// the authentication result is matched against the UDR, not against this instance's memory,
+	// so that it can be removed after a restart or through another UDM instance
+	ue, ok := p.Context().UdmUeFindBySupi(supi)
+	if !ok {
+		ue = p.Context().NewUdmUe(supi)
+	}
+	ue.AuthEventLock.Lock()
+	defer ue.AuthEventLock.Unlock()
+
+	var queryAuthStatusRequest Nudr_DataRepository.QueryAuthenticationStatusRequest
+	queryAuthStatusRequest.UeId = &supi
+
+	queryAuthStatusResponse, err := client.AuthEventDocumentApi.QueryAuthenticationStatus(
+		ctx, &queryAuthStatusRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if !ok {
+			logger.UeauLog.Errorln("DeleteAuth err:", err.Error())
+			problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		if apiError.ErrorStatus != http.StatusNotFound {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.JSON(apiError.ErrorStatus, apiError.RawBody)
+			return
+		}
+	}
+	if err != nil || udm_context.AuthEventId(supi, &queryAuthStatusResponse.AuthEvent) != authEventId {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "DATA_NOT_FOUND",
+			Detail: fmt.Sprintf("authEventId [%s] not found", authEventId),
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var deleteAuthStatusRequest Nudr_DataRepository.DeleteAuthenticationStatusRequest
+	deleteAuthStatusRequest.UeId = &supi
+
+	_, err = client.AuthEventDocumentApi.DeleteAuthenticationStatus(ctx, &deleteAuthStatusRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.JSON(apiError.ErrorStatus, apiError.RawBody)
+			return
+		}
+		logger.UeauLog.Errorln("DeleteAuth err:", err.Error())
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (p *Processor) GenerateAuthDataProcedure(
@@ -122,6 +315,11 @@ func (p *Processor) GenerateAuthDataProcedure(
 	authInfoRequest models.AuthenticationInfoRequest,
 	supiOrSuci string,
 ) {
+	if !p.authorizeServingNetwork(c, authInfoRequest.ServingNetworkName) ||
+		!p.authorizeAusf(c, authInfoRequest.AusfInstanceId) {
+		return
+	}
+
 	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
 	if err != nil {
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
@@ -132,7 +330,7 @@ func (p *Processor) GenerateAuthDataProcedure(
 
 	response := &models.UdmUeauAuthenticationInfoResult{}
 	rand.New(rand.NewSource(time.Now().UnixNano()))
-	supi, err := suci.ToSupi(supiOrSuci, p.Context().SuciProfiles)
+	supi, err := p.Context().SuciToSupi(supiOrSuci)
 	if err != nil {
 		problemDetails := &models.ProblemDetails{
 			Status: http.StatusForbidden,
@@ -148,12 +346,134 @@ func (p *Processor) GenerateAuthDataProcedure(
 
 	logger.UeauLog.Tracef("supi conversion => [%s]", supi)
 
+	if !p.checkAuthLockout(c, supi) {
+		return
+	}
+
+	// the SQN read from UDR stays valid until the vectors are generated
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubs, ok := p.queryAuthSubscription(ctx, c, supi)
+	if !ok {
+		return
+	}
+
+	// 5G AKA unless the subscription asks for EAP-AKA', TS 33.501 clause 6.1.2
+	authMethod := authSubs.AuthenticationMethod
+	if authMethod == "" {
+		authMethod = models.AuthMethod__5_G_AKA
+	}
+	if authMethod != models.AuthMethod__5_G_AKA && authMethod != models.AuthMethod_EAP_AKA_PRIME {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: fmt.Sprintf("unsupported authentication method [%s]", authMethod),
+		}
+
+		logger.UeauLog.Errorf("Unsupported authentication method [%s]", authMethod)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	indKey := sqnIndKey(authInfoRequest.ServingNetworkName, authInfoRequest.AusfInstanceId)
+	vectors, ok := p.generateAuthVectors(ctx, c, supi, authSubs, indKey, 1, authInfoRequest.ResynchronizationInfo,
+		&udm_context.SqnAuditRecord{
+			ServingNetworkName: authInfoRequest.ServingNetworkName,
+			RequesterNfId:      authInfoRequest.AusfInstanceId,
+		})
+	if !ok {
+		return
+	}
+	RAND, AUTN, RES := vectors[0].Rand, vectors[0].Autn, vectors[0].Xres
+	CK, IK, SQNxorAK := vectors[0].Ck, vectors[0].Ik, vectors[0].SqnXorAk
+
+	var av models.AuthenticationVector
+	if authMethod == models.AuthMethod__5_G_AKA {
+		response.AuthType = models.UdmUeauAuthType__5_G_AKA
+
+		xresStar, kausf, err := deriveXresStarKausf(CK, IK, authInfoRequest.ServingNetworkName, RAND, RES, SQNxorAK)
+		if err != nil {
+			problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+			logger.UeauLog.Errorf("Derive XRES* and KAUSF err: %+v", err)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		logger.UeauLog.Tracef("xresStar=[%x]", xresStar)
+		logger.UeauLog.Tracef("Kausf=[%x]", kausf)
+
+		// Fill in rand, xresStar, autn, kausf
+		av.Rand = hex.EncodeToString(RAND)
+		av.XresStar = hex.EncodeToString(xresStar)
+		av.Autn = hex.EncodeToString(AUTN)
+		av.Kausf = hex.EncodeToString(kausf)
+		av.AvType = models.AvType__5_G_HE_AKA
+	} else { // EAP-AKA'
+		response.AuthType = models.UdmUeauAuthType_EAP_AKA_PRIME
+
+		ckPrime, ikPrime, err := deriveCkPrimeIkPrime(CK, IK, authInfoRequest.ServingNetworkName, SQNxorAK)
+		if err != nil {
+			problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+			logger.UeauLog.Errorf("Derive CK' and IK' err: %+v", err)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		logger.UeauLog.Tracef("ckPrime=[%x], ikPrime=[%x]", ckPrime, ikPrime)
+
+		// Fill in rand, xres, autn, ckPrime, ikPrime (AvEapAkaPrime)
+		av.Rand = hex.EncodeToString(RAND)
+		av.Xres = hex.EncodeToString(RES)
+		av.Autn = hex.EncodeToString(AUTN)
+		av.CkPrime = hex.EncodeToString(ckPrime)
+		av.IkPrime = hex.EncodeToString(ikPrime)
+		av.AvType = models.AvType_EAP_AKA_PRIME
+	}
+
+	response.AuthenticationVector = &av
+	response.Supi = supi
+	c.JSON(http.StatusOK, response)
+}
+
+// queryAuthSubscription reads the authentication subscription of supi from the
+// UDR and decrypts its keys. On failure the error response has been written.
+func (p *Processor) queryAuthSubscription(ctx context.Context, c *gin.Context, supi string) (
+	*models.AuthenticationSubscription, bool,
+) {
+	authSubs, ok := p.queryAuthSubscriptionData(ctx, c, supi)
+	if !ok {
+		return nil, false
+	}
+
+	if err := p.decryptAuthSubscriptionKeys(supi, authSubs); err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("decrypt subscriber keys err:", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return nil, false
+	}
+
+	return authSubs, true
+}
+
+// queryAuthSubscriptionData queries the authentication subscription from UDR
+// with the subscriber keys left encrypted.
+func (p *Processor) queryAuthSubscriptionData(ctx context.Context, c *gin.Context, supi string) (
+	*models.AuthenticationSubscription, bool,
+) {
 	client, err := p.Consumer().CreateUDMClientToUDR(supi)
 	if err != nil {
 		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
 	}
 	var queryAuthSubsDataRequest Nudr_DataRepository.QueryAuthSubsDataRequest
 	queryAuthSubsDataRequest.UeId = &supi
@@ -171,12 +491,65 @@ func (p *Processor) GenerateAuthDataProcedure(
 			default:
 				logger.UeauLog.Errorln("Return from UDR QueryAuthSubsData error")
 			}
-			return
+			return nil, false
 		}
 		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
+	}
+
+	return &authSubs.AuthenticationSubscription, true
+}
+
+// authVector is a UMTS AKA quintet together with SQN xor AK, from which the
+// access specific authentication vectors are derived.
+type authVector struct {
+	Rand     []byte
+	Autn     []byte
+	Xres     []byte
+	Ck       []byte
+	Ik       []byte
+	SqnXorAk []byte
+	Sqn      []byte
+}
+
+// generateAuthVectors generates numVectors authentication vectors for supi,
+// resynchronising SQN with resyncInfo when present, and stores the new SQN in
+// the UDR. The outcome is added to the SQN audit trail with the requester
+// details from audit. On failure the error response has been written.
+func (p *Processor) generateAuthVectors(
+	ctx context.Context,
+	c *gin.Context,
+	supi string,
+	authSubs *models.AuthenticationSubscription,
+	indKey string,
+	numVectors int,
+	resyncInfo *models.ResynchronizationInfo,
+	audit *udm_context.SqnAuditRecord,
+) ([]authVector, bool) {
+	audit.Supi = supi
+
+	// single vectors are served from a batch kept in the vector pool
+	var poolKey string
+	if p.vectorPool != nil && numVectors == 1 {
+		poolKey = vectorPoolKey(authSubs, indKey)
+		if resyncInfo != nil {
+			p.vectorPool.flush(supi)
+		} else if vector, ok := p.vectorPool.take(supi, poolKey, authSubs); ok {
+			audit.Sqns, audit.Ind = auditSqns([]authVector{vector})
+			p.auditSqn(audit, udm_context.SqnAuditPooled, "")
+			return []authVector{vector}, true
+		}
+		numVectors = p.vectorPool.size
+	}
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return nil, false
 	}
 
 	/*
@@ -185,12 +558,27 @@ func (p *Processor) GenerateAuthDataProcedure(
 		AMF: 16 bits (2 bytes) (hex len = 4) TS33.102 - Annex H
 	*/
 
+	alg, ok := util.GetAuthAlgorithm(authSubs.AlgorithmId)
+	if !ok {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: fmt.Sprintf("unsupported algorithmId [%s]", authSubs.AlgorithmId),
+		}
+
+		logger.UeauLog.Errorf("Unsupported algorithmId [%s] of supi=[%s]", authSubs.AlgorithmId, supi)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return nil, false
+	}
+	algParams := alg.Params()
+
 	hasOPC := false
-	var kStr, opcStr string
+	var kStr string
 	var k, op, opc []byte
-	if authSubs.AuthenticationSubscription.EncPermanentKey != "" {
-		kStr = authSubs.AuthenticationSubscription.EncPermanentKey
-		if len(kStr) == keyStrLen {
+	if authSubs.EncPermanentKey != "" {
+		kStr = authSubs.EncPermanentKey
+		if len(kStr)%2 == 0 && slices.Contains(algParams.KeyLengths, len(kStr)/2) {
 			k, err = hex.DecodeString(kStr)
 			if err != nil {
 				logger.UeauLog.Errorln("err:", err)
@@ -205,7 +593,7 @@ func (p *Processor) GenerateAuthDataProcedure(
 			logger.UeauLog.Errorln("kStr length is ", len(kStr))
 			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 			c.JSON(int(problemDetails.Status), problemDetails)
-			return
+			return nil, false
 		}
 	} else {
 		problemDetails := &models.ProblemDetails{
@@ -217,12 +605,24 @@ func (p *Processor) GenerateAuthDataProcedure(
 		logger.UeauLog.Errorln("Nil PermanentKey")
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
 	}
 
-	if authSubs.AuthenticationSubscription.EncOpcKey != "" {
-		opcStr = authSubs.AuthenticationSubscription.EncOpcKey
-		if len(opcStr) == opcStrLen {
+	// The OPc, or the 256-bit TOPc for TUAK, is provisioned per subscriber or
+	// derived from the configured OP (TOP)
+	opcStr, opStr := authSubs.EncOpcKey, ""
+	operatorKeys := p.Context().OperatorKeys
+	if strings.EqualFold(authSubs.AlgorithmId, util.AuthAlgorithmTuak) {
+		opcStr = authSubs.EncTopcKey
+		if operatorKeys != nil {
+			opStr = operatorKeys.Top
+		}
+	} else if operatorKeys != nil {
+		opStr = operatorKeys.Op
+	}
+
+	if opcStr != "" {
+		if len(opcStr) == algParams.OpcLength*2 {
 			opc, err = hex.DecodeString(opcStr)
 			if err != nil {
 				logger.UeauLog.Errorln("err:", err)
@@ -232,37 +632,37 @@ func (p *Processor) GenerateAuthDataProcedure(
 		} else {
 			logger.UeauLog.Errorln("opcStr length is ", len(opcStr))
 		}
+	} else if opStr != "" {
+		// Only OP is provisioned, OPc = AES_K(OP) XOR OP (TS 35.206), TOPc as of TS 35.231
+		logger.UeauLog.Infoln("Nil Opc, derive it from OP")
+		op, err = hex.DecodeString(opStr)
+		if err != nil || len(op) != algParams.OpcLength {
+			logger.UeauLog.Errorln("opStr length is ", len(opStr))
+		} else if opc, err = p.deriveOpc(alg, supi, k, op); err != nil {
+			logger.UeauLog.Errorln("derive OPc err:", err)
+		} else {
+			hasOPC = true
+		}
 	} else {
 		logger.UeauLog.Infoln("Nil Opc")
 	}
 
 	if !hasOPC {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
+		detail := "no usable OPc or OP in authentication subscription"
+		if opcStr == "" && opStr == "" {
+			detail = "neither OPc nor OP is provisioned"
 		}
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
-	}
-
-	sqnStr := p.strictHex(authSubs.AuthenticationSubscription.SequenceNumber.Sqn, 12)
-	logger.UeauLog.Traceln("sqnStr", sqnStr)
-	sqn, err := hex.DecodeString(sqnStr)
-	if err != nil {
 		problemDetails := &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
-			Detail: err.Error(),
+			Detail: detail,
 		}
-
-		logger.UeauLog.Errorln("err:", err)
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
 	}
 
-	logger.UeauLog.Tracef("K=[%x], sqn=[%x], OP=[%x], OPC=[%x]", k, sqn, op, opc)
+	logger.UeauLog.Tracef("K=[%x], OP=[%x], OPC=[%x]", k, op, opc)
 
 	RAND := make([]byte, 16)
 	_, err = cryptoRand.Read(RAND)
@@ -276,10 +676,10 @@ func (p *Processor) GenerateAuthDataProcedure(
 		logger.UeauLog.Errorln("err:", err)
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
 	}
 
-	amfStr := p.strictHex(authSubs.AuthenticationSubscription.AuthenticationManagementField, 4)
+	amfStr := p.strictHex(authSubs.AuthenticationManagementField, 4)
 	logger.UeauLog.Traceln("amfStr", amfStr)
 	AMF, err := hex.DecodeString(amfStr)
 	if err != nil {
@@ -292,16 +692,17 @@ func (p *Processor) GenerateAuthDataProcedure(
 		logger.UeauLog.Errorln("err:", err)
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
 	}
 
 	logger.UeauLog.Tracef("RAND=[%x], AMF=[%x]", RAND, AMF)
 
 	// re-synchronization
-	if authInfoRequest.ResynchronizationInfo != nil {
+	var SQNms []byte
+	if resyncInfo != nil {
 		logger.UeauLog.Infof("Authentication re-synchronization")
 
-		Auts, deCodeErr := hex.DecodeString(authInfoRequest.ResynchronizationInfo.Auts)
+		Auts, deCodeErr := hex.DecodeString(resyncInfo.Auts)
 		if deCodeErr != nil {
 			problemDetails := &models.ProblemDetails{
 				Status: http.StatusForbidden,
@@ -312,10 +713,10 @@ func (p *Processor) GenerateAuthDataProcedure(
 			logger.UeauLog.Errorln("err:", deCodeErr)
 			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 			c.JSON(int(problemDetails.Status), problemDetails)
-			return
+			return nil, false
 		}
 
-		randHex, deCodeErr := hex.DecodeString(authInfoRequest.ResynchronizationInfo.Rand)
+		randHex, deCodeErr := hex.DecodeString(resyncInfo.Rand)
 		if deCodeErr != nil {
 			problemDetails := &models.ProblemDetails{
 				Status: http.StatusForbidden,
@@ -326,10 +727,24 @@ func (p *Processor) GenerateAuthDataProcedure(
 			logger.UeauLog.Errorln("err:", deCodeErr)
 			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 			c.JSON(int(problemDetails.Status), problemDetails)
-			return
+			return nil, false
+		}
+
+		if len(Auts) != 6+algParams.MacLength {
+			problemDetails := &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: "invalid AUTS length",
+			}
+
+			logger.UeauLog.Errorln("AUTS length is ", len(Auts))
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return nil, false
 		}
 
-		SQNms, macS := p.aucSQN(opc, k, Auts, randHex)
+		var macS []byte
+		SQNms, macS = p.aucSQN(alg, opc, k, Auts, randHex)
 		if reflect.DeepEqual(macS, Auts[6:]) {
 			_, err = cryptoRand.Read(RAND)
 			if err != nil {
@@ -342,187 +757,340 @@ func (p *Processor) GenerateAuthDataProcedure(
 				logger.UeauLog.Errorln("err:", err)
 				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 				c.JSON(int(problemDetails.Status), problemDetails)
-				return
+				return nil, false
 			}
-
-			// increment sqn authSubs.SequenceNumber
-			bigSQN := big.NewInt(0)
-			sqnStr = hex.EncodeToString(SQNms)
-			logger.UeauLog.Tracef("SQNstr=[%s]", sqnStr)
-			bigSQN.SetString(sqnStr, 16)
-
-			bigInc := big.NewInt(ind + 1)
-
-			bigP := big.NewInt(SqnMAx)
-			bigSQN = bigInc.Add(bigSQN, bigInc)
-			bigSQN = bigSQN.Mod(bigSQN, bigP)
-			sqnStr = fmt.Sprintf("%x", bigSQN)
-			sqnStr = p.strictHex(sqnStr, 12)
 		} else {
-			logger.UeauLog.Errorf("Re-Sync MAC failed for UE with identity supiOrSuci=[%s], resolvedSupi=[%s]", supiOrSuci, supi)
-			logger.UeauLog.Errorln("MACS ", macS)
-			logger.UeauLog.Errorln("Auts[6:] ", Auts[6:])
-			logger.UeauLog.Errorln("Sqn ", SQNms)
+			logger.UeauLog.Errorf("Re-Sync MAC failed for UE with identity supi=[%s]: SQN_MS=[%x] XMAC-S=[%x] MAC-S=[%x]",
+				supi, SQNms, macS, Auts[6:])
+			audit.SqnMs = hex.EncodeToString(SQNms)
+			p.auditSqn(audit, udm_context.SqnAuditMacFailure, "MAC-S verification failed")
+			if p.authLockout != nil {
+				p.authLockout.fail(supi)
+			}
 			problemDetails := &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  "modification is rejected",
 			}
 			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 			c.JSON(int(problemDetails.Status), problemDetails)
-			return
+			return nil, false
 		}
 	}
 
-	// increment sqn
-	bigSQN := big.NewInt(0)
-	sqn, err = hex.DecodeString(sqnStr)
+	if SQNms != nil {
+		audit.SqnMs = hex.EncodeToString(SQNms)
+	}
+	sqns, err := p.reserveSqns(ctx, client, supi, authSubs.SequenceNumber, SQNms, indKey, numVectors, audit)
 	if err != nil {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: err.Error(),
+		if SQNms != nil && errors.Is(err, errInvalidSqn) {
+			p.auditSqn(audit, udm_context.SqnAuditResyncRejected, err.Error())
+		} else {
+			p.auditSqn(audit, udm_context.SqnAuditUpdateFailed, err.Error())
 		}
 
-		logger.UeauLog.Errorln("err:", err)
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
-	}
-
-	bigSQN.SetString(sqnStr, 16)
-
-	bigInc := big.NewInt(1)
-	bigSQN = bigInc.Add(bigSQN, bigInc)
-
-	SQNheStr := fmt.Sprintf("%x", bigSQN)
-	SQNheStr = p.strictHex(SQNheStr, 12)
-	patchItemArray := []models.PatchItem{
-		{
-			Op:   models.PatchOperation_REPLACE,
-			Path: "/sequenceNumber",
-			Value: models.SequenceNumber{
-				Sqn: SQNheStr,
-			},
-		},
-	}
-
-	logger.ProcLog.Infoln("ModifyAuthenticationSubscriptionRequest: ", patchItemArray)
-
-	var modifyAuthenticationSubscriptionRequest Nudr_DataRepository.ModifyAuthenticationSubscriptionRequest
-	modifyAuthenticationSubscriptionRequest.UeId = &supi
-	modifyAuthenticationSubscriptionRequest.PatchItem = patchItemArray
-	_, err = client.AuthenticationSubscriptionDocumentApi.ModifyAuthenticationSubscription(
-		ctx, &modifyAuthenticationSubscriptionRequest)
-	if err != nil {
 		problemDetails := &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  "modification is rejected ",
 			Detail: err.Error(),
 		}
+		if errors.Is(err, errInvalidSqn) {
+			problemDetails.Cause = authenticationRejected
+		}
 
 		logger.UeauLog.Errorln("update sqn error:", err)
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, false
 	}
 
-	// Run milenage
-	macA, macS := make([]byte, 8), make([]byte, 8)
-	CK, IK := make([]byte, 16), make([]byte, 16)
-	RES := make([]byte, 8)
-	AK, AKstar := make([]byte, 6), make([]byte, 6)
+	vectors := make([]authVector, 0, numVectors)
+	for n := 0; n < numVectors; n++ {
+		sqn := sqns[n]
+		if n > 0 {
+			RAND = make([]byte, 16)
+			if _, err = cryptoRand.Read(RAND); err != nil {
+				problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+				logger.UeauLog.Errorln("err:", err)
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+				c.JSON(int(problemDetails.Status), problemDetails)
+				return nil, false
+			}
+		}
 
-	// Generate macA, macS
-	err = util.MilenageF1(opc, k, RAND, sqn, AMF, macA, macS)
-	if err != nil {
-		logger.UeauLog.Errorln("milenage F1 err:", err)
+		macA := make([]byte, algParams.MacLength)
+		CK, IK := make([]byte, algParams.CkLength), make([]byte, algParams.IkLength)
+		RES := make([]byte, algParams.ResLength)
+		AK := make([]byte, 6)
+
+		// Generate macA
+		err = alg.F1(opc, k, RAND, sqn, AMF, macA)
+		if err != nil {
+			logger.UeauLog.Errorln("F1 err:", err)
+		}
+
+		// Generate RES, CK, IK, AK
+		// RES == XRES (expected RES) for server
+		err = alg.F2345(opc, k, RAND, RES, CK, IK, AK)
+		if err != nil {
+			logger.UeauLog.Errorln("F2345 err:", err)
+		}
+		logger.UeauLog.Tracef("RES=[%s]", hex.EncodeToString(RES))
+
+		// Generate AUTN
+		logger.UeauLog.Tracef("SQN=[%x], AK=[%x]", sqn, AK)
+		logger.UeauLog.Tracef("AMF=[%x], macA=[%x]", AMF, macA)
+		SQNxorAK := make([]byte, 6)
+		for i := 0; i < len(sqn); i++ {
+			SQNxorAK[i] = sqn[i] ^ AK[i]
+		}
+		logger.UeauLog.Tracef("SQN xor AK=[%x]", SQNxorAK)
+		AUTN := append(append([]byte{}, SQNxorAK...), AMF...)
+		AUTN = append(AUTN, macA...)
+		logger.UeauLog.Tracef("AUTN=[%x]", AUTN)
+
+		vectors = append(vectors, authVector{
+			Rand:     RAND,
+			Autn:     AUTN,
+			Xres:     RES,
+			Ck:       CK,
+			Ik:       IK,
+			SqnXorAk: SQNxorAK,
+			Sqn:      sqn,
+		})
 	}
+	ueau_metrics.IncrVectorsGenerated(len(vectors))
 
-	// Generate RES, CK, IK, AK, AKstar
-	// RES == XRES (expected RES) for server
-	err = util.MilenageF2345(opc, k, RAND, RES, CK, IK, AK, AKstar)
-	if err != nil {
-		logger.UeauLog.Errorln("milenage F2345 err:", err)
+	if poolKey != "" {
+		p.vectorPool.put(supi, poolKey, vectors[1:], audit.NewSqn, authSubs.AuthenticationManagementField)
+		vectors = vectors[:1]
 	}
-	logger.UeauLog.Tracef("milenage RES=[%s]", hex.EncodeToString(RES))
 
-	// Generate AUTN
-	logger.UeauLog.Tracef("SQN=[%x], AK=[%x]", sqn, AK)
-	logger.UeauLog.Tracef("AMF=[%x], macA=[%x]", AMF, macA)
-	SQNxorAK := make([]byte, 6)
-	for i := 0; i < len(sqn); i++ {
-		SQNxorAK[i] = sqn[i] ^ AK[i]
+	audit.Sqns, audit.Ind = auditSqns(vectors)
+	if SQNms != nil {
+		logger.UeauLog.Infof("SQN of supi=[%s] re-synchronised with SQN_MS=[%s]: SQN_HE [%s] -> [%s]",
+			supi, audit.SqnMs, audit.OldSqn, audit.NewSqn)
+		p.auditSqn(audit, udm_context.SqnAuditResynced, "")
+	} else {
+		p.auditSqn(audit, udm_context.SqnAuditIssued, "")
 	}
-	logger.UeauLog.Tracef("SQN xor AK=[%x]", SQNxorAK)
-	AUTN := append(append(SQNxorAK, AMF...), macA...)
-	logger.UeauLog.Tracef("AUTN=[%x]", AUTN)
+	return vectors, true
+}
 
-	var av models.AuthenticationVector
-	if authSubs.AuthenticationSubscription.AuthenticationMethod == models.AuthMethod__5_G_AKA {
-		response.AuthType = models.UdmUeauAuthType__5_G_AKA
+// auditSqn adds audit with outcome to the SQN audit trail
+func (p *Processor) auditSqn(audit *udm_context.SqnAuditRecord, outcome, cause string) {
+	audit.Outcome, audit.Cause = outcome, cause
+	p.Context().AddSqnAuditRecord(*audit)
+	ueau_metrics.IncrSqnAuditCounter(outcome)
+}
 
-		// derive XRES*
-		key := append(CK, IK...)
-		FC := ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION
-		P0 := []byte(authInfoRequest.ServingNetworkName)
-		P1 := RAND
-		P2 := RES
+// auditSqns returns the SQNs of vectors and, with SQN management, their IND
+func auditSqns(vectors []authVector) ([]string, *int32) {
+	sqns := make([]string, 0, len(vectors))
+	for _, vector := range vectors {
+		sqns = append(sqns, hex.EncodeToString(vector.Sqn))
+	}
+	m := util.GetSqnManager()
+	if m == nil || len(vectors) == 0 {
+		return sqns, nil
+	}
+	ind := int32(sqnToUint64(vectors[0].Sqn) & (1<<m.IndLength() - 1))
+	return sqns, &ind
+}
 
-		kdfValForXresStar, err := ueauth.GetKDFValue(
-			key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1), P2, ueauth.KDFLen(P2))
+// reserveSqns allocates the SQNs of numVectors vectors following the stored
+// sequence number, or SQN_MS on re-synchronisation, and stores the new SQN_HE
+// in UDR. The update is conditional on the SQN read so that concurrent
+// requests do not hand out the same SQN; on a conflict the sequence number is
+// read again and the allocation retried. The SQN_HE read and stored are set
+// in audit.
+func (p *Processor) reserveSqns(
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+	stored *models.SequenceNumber,
+	sqnMs []byte,
+	indKey string,
+	numVectors int,
+	audit *udm_context.SqnAuditRecord,
+) ([][]byte, error) {
+	lastUse := make(map[string]time.Time)
+	if ue, ok := p.Context().UdmUeFindBySupi(supi); ok {
+		lastUse = ue.IndLastUse()
+	}
+	for attempt := 1; ; attempt++ {
+		patchItemArray := []models.PatchItem{sqnPrecondition(stored)}
+		if stored == nil {
+			stored = &models.SequenceNumber{}
+			patchItemArray = append(patchItemArray, models.PatchItem{
+				Op:   models.PatchOperation_ADD,
+				Path: "/sequenceNumber",
+			})
+		} else {
+			patchItemArray = append(patchItemArray, models.PatchItem{
+				Op:   models.PatchOperation_REPLACE,
+				Path: "/sequenceNumber",
+			})
+		}
+		audit.OldSqn, audit.NewSqn = stored.Sqn, ""
+		sqns, sequenceNumber, err := p.nextSequenceNumber(stored, sqnMs, indKey, lastUse, numVectors)
 		if err != nil {
-			logger.UeauLog.Errorf("Get kdfValForXresStar err: %+v", err)
+			return nil, fmt.Errorf("%w: %w", errInvalidSqn, err)
 		}
-		xresStar := kdfValForXresStar[len(kdfValForXresStar)/2:]
-		logger.UeauLog.Tracef("xresStar=[%x]", xresStar)
+		patchItemArray[1].Value = sequenceNumber
+
+		logger.ProcLog.Infoln("ModifyAuthenticationSubscriptionRequest: ", patchItemArray)
+
+		var modifyAuthenticationSubscriptionRequest Nudr_DataRepository.ModifyAuthenticationSubscriptionRequest
+		modifyAuthenticationSubscriptionRequest.UeId = &supi
+		modifyAuthenticationSubscriptionRequest.PatchItem = patchItemArray
+		_, err = client.AuthenticationSubscriptionDocumentApi.ModifyAuthenticationSubscription(
+			ctx, &modifyAuthenticationSubscriptionRequest)
+		if err == nil {
+			audit.NewSqn = sequenceNumber.Sqn
+			ueau_metrics.IncrSqnUpdateCounter(metrics_utils.SuccessMetric)
+			return sqns, nil
+		}
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if !ok || (apiError.ErrorStatus != http.StatusConflict && apiError.ErrorStatus != http.StatusPreconditionFailed) {
+			ueau_metrics.IncrSqnUpdateCounter(metrics_utils.FailureMetric)
+			return nil, err
+		}
+		ueau_metrics.IncrSqnUpdateCounter(ueau_metrics.SqnUpdateConflict)
+		if attempt >= maxSqnUpdateAttempts {
+			return nil, fmt.Errorf("SQN of %s modified concurrently, giving up after %d attempts", supi, attempt)
+		}
+		logger.UeauLog.Warnf("SQN of %s modified concurrently, retrying", supi)
 
-		// derive Kausf
-		FC = ueauth.FC_FOR_KAUSF_DERIVATION
-		P0 = []byte(authInfoRequest.ServingNetworkName)
-		P1 = SQNxorAK
-		kdfValForKausf, err := ueauth.GetKDFValue(key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
+		var queryAuthSubsDataRequest Nudr_DataRepository.QueryAuthSubsDataRequest
+		queryAuthSubsDataRequest.UeId = &supi
+		authSubs, err := client.AuthenticationDataDocumentApi.QueryAuthSubsData(ctx, &queryAuthSubsDataRequest)
 		if err != nil {
-			logger.UeauLog.Errorf("Get kdfValForKausf err: %+v", err)
+			return nil, err
 		}
-		logger.UeauLog.Tracef("Kausf=[%x]", kdfValForKausf)
+		stored = authSubs.AuthenticationSubscription.SequenceNumber
+	}
+}
 
-		// Fill in rand, xresStar, autn, kausf
-		av.Rand = hex.EncodeToString(RAND)
-		av.XresStar = hex.EncodeToString(xresStar)
-		av.Autn = hex.EncodeToString(AUTN)
-		av.Kausf = hex.EncodeToString(kdfValForKausf)
-		av.AvType = models.AvType__5_G_HE_AKA
-	} else { // EAP-AKA'
-		response.AuthType = models.UdmUeauAuthType_EAP_AKA_PRIME
-		// derive CK' and IK'
-		key := append(CK, IK...)
-		FC := ueauth.FC_FOR_CK_PRIME_IK_PRIME_DERIVATION
-		P0 := []byte(authInfoRequest.ServingNetworkName)
-		P1 := SQNxorAK
-		kdfVal, err := ueauth.GetKDFValue(key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
-		if err != nil {
-			logger.UeauLog.Errorf("Get kdfVal err: %+v", err)
+// sqnPrecondition returns the PATCH test of the sequence number read from UDR,
+// so that the update fails if it was created or modified since. An absent
+// sequence number or SQN is tested against null.
+func sqnPrecondition(stored *models.SequenceNumber) models.PatchItem {
+	switch {
+	case stored == nil:
+		return models.PatchItem{
+			Op:    models.PatchOperation_TEST,
+			Path:  "/sequenceNumber",
+			Value: json.RawMessage("null"),
+		}
+	case stored.Sqn == "":
+		return models.PatchItem{
+			Op:    models.PatchOperation_TEST,
+			Path:  "/sequenceNumber/sqn",
+			Value: json.RawMessage("null"),
 		}
-		logger.UeauLog.Tracef("kdfVal=[%x] (len=%d)", kdfVal, len(kdfVal))
+	default:
+		return models.PatchItem{
+			Op:    models.PatchOperation_TEST,
+			Path:  "/sequenceNumber/sqn",
+			Value: stored.Sqn,
+		}
+	}
+}
 
-		// For TS 35.208 test set 19 & RFC 5448 test vector 1
-		// CK': 0093 962d 0dd8 4aa5 684b 045c 9edf fa04
-		// IK': ccfc 230c a74f cc96 c0a5 d611 64f5 a76
+// nextSequenceNumber returns the SQNs of numVectors vectors and the sequence
+// number to store in UDR.
+func (p *Processor) nextSequenceNumber(
+	stored *models.SequenceNumber,
+	sqnMs []byte,
+	indKey string,
+	lastUse map[string]time.Time,
+	numVectors int,
+) ([][]byte, models.SequenceNumber, error) {
+	sqnStr := p.strictHex(stored.Sqn, 12)
+	logger.UeauLog.Traceln("sqnStr", sqnStr)
+	sqn, err := hex.DecodeString(sqnStr)
+	if err != nil {
+		return nil, models.SequenceNumber{}, err
+	}
 
-		ckPrime := kdfVal[:len(kdfVal)/2]
-		ikPrime := kdfVal[len(kdfVal)/2:]
-		logger.UeauLog.Tracef("ckPrime=[%x], kPrime=[%x]", ckPrime, ikPrime)
+	sqnManager := util.GetSqnManager()
+	if sqnMs != nil {
+		if sqnManager != nil {
+			if sqnManager.Stale(sqnToUint64(sqn), sqnToUint64(sqnMs)) {
+				logger.UeauLog.Warnf("SQN_MS [%x] lags SQN_HE [%x] by the freshness limit or more", sqnMs, sqn)
+			}
+			sqn = uint64ToSqn(sqnManager.Resync(sqnToUint64(sqn), sqnToUint64(sqnMs)))
+		} else {
+			// increment sqn authSubs.SequenceNumber
+			sqn = sqnAdd(sqnMs, ind+1)
+		}
+	}
 
-		// Fill in rand, xres, autn, ckPrime, ikPrime
-		av.Rand = hex.EncodeToString(RAND)
-		av.Xres = hex.EncodeToString(RES)
-		av.Autn = hex.EncodeToString(AUTN)
-		av.CkPrime = hex.EncodeToString(ckPrime)
-		av.IkPrime = hex.EncodeToString(ikPrime)
-		av.AvType = models.AvType_EAP_AKA_PRIME
+	if sqnManager != nil {
+		sqns, sequenceNumber := nextSqns(sqnManager, stored, sqn, indKey, lastUse, numVectors)
+		return sqns, sequenceNumber, nil
 	}
 
-	response.AuthenticationVector = &av
-	response.Supi = supi
-	c.JSON(http.StatusOK, response)
+	// one SQN per vector
+	sqns := make([][]byte, 0, numVectors)
+	for n := 0; n < numVectors; n++ {
+		sqns = append(sqns, sqnAdd(sqn, int64(n)))
+	}
+	return sqns, models.SequenceNumber{
+		Sqn: hex.EncodeToString(sqnAdd(sqn, int64(numVectors))),
+	}, nil
+}
+
+// sqnIndKey is the requester the IND of the SQN is allocated to
+func sqnIndKey(servingNetwork, ausfInstanceId string) string {
+	if m := util.GetSqnManager(); m != nil && m.IndByAusf() && ausfInstanceId != "" {
+		return ausfInstanceId
+	}
+	return servingNetwork
+}
+
+// nextSqns returns the SQNs of numVectors vectors with the IND allocated to
+// indKey, and the sequence number to store in UDR. lastUse tells when the IND
+// keys were last used.
+func nextSqns(
+	m *util.SqnManager,
+	stored *models.SequenceNumber,
+	sqnHe []byte,
+	indKey string,
+	lastUse map[string]time.Time,
+	numVectors int,
+) ([][]byte, models.SequenceNumber) {
+	lastIndexes := make(map[string]int32)
+	// allocations made with another IND length are void
+	if stored != nil && (stored.IndLength == 0 || stored.IndLength == m.IndLength()) {
+		for key, ind := range stored.LastIndexes {
+			lastIndexes[key] = ind
+		}
+	}
+	ind := m.AllocateInd(lastIndexes, lastUse, indKey)
+
+	sqnValues, newSqnHe := m.Generate(sqnToUint64(sqnHe), ind, numVectors)
+	sqns := make([][]byte, 0, numVectors)
+	for _, sqn := range sqnValues {
+		sqns = append(sqns, uint64ToSqn(sqn))
+	}
+	return sqns, models.SequenceNumber{
+		SqnScheme:   m.SqnScheme(),
+		Sqn:         hex.EncodeToString(uint64ToSqn(newSqnHe)),
+		LastIndexes: lastIndexes,
+		IndLength:   m.IndLength(),
+	}
+}
+
+func sqnToUint64(sqn []byte) uint64 {
+	return new(big.Int).SetBytes(sqn).Uint64()
+}
+
+func uint64ToSqn(sqn uint64) []byte {
+	return new(big.Int).SetUint64(sqn & util.SqnMask).FillBytes(make([]byte, 6))
+}
+
+// sqnAdd returns the 48-bit SQN incremented by n.
+func sqnAdd(sqn []byte, n int64) []byte {
+	return uint64ToSqn(sqnToUint64(sqn) + uint64(n))
 }
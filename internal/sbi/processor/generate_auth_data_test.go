@@ -1,9 +1,17 @@
 package processor
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/h2non/gock"
@@ -14,7 +22,10 @@ import (
 	"github.com/free5gc/openapi/models"
 	udm_context "github.com/free5gc/udm/internal/context"
 	"github.com/free5gc/udm/internal/sbi/consumer"
+	"github.com/free5gc/udm/internal/util"
+	"github.com/free5gc/udm/pkg/factory"
 	mockapp "github.com/free5gc/udm/pkg/mockapp"
+	"github.com/free5gc/util/milenage"
 )
 
 func TestGenerateAuthDataProcedure(t *testing.T) {
@@ -69,7 +80,7 @@ func TestGenerateAuthDataProcedure(t *testing.T) {
 	).AnyTimes()
 
 	authInfoReq := models.AuthenticationInfoRequest{
-		ServingNetworkName: "internet",
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
 	}
 	httpRecorder := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(httpRecorder)
@@ -110,3 +121,827 @@ func TestGenerateAuthDataProcedure(t *testing.T) {
 	require.Equal(t, expectResponse.Supi, res.Supi)
 	require.Equal(t, expectResponse.AuthenticationVector.AvType, res.AuthenticationVector.AvType)
 }
+
+func TestGenerateAuthDataProcedureOpcDerivation(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000002"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	defer func() { udm_context.GetSelf().OperatorKeys = nil }()
+
+	testCases := []struct {
+		name         string
+		algorithmId  string
+		k            string
+		encOpcKey    string
+		encTopcKey   string
+		operatorKeys *factory.OperatorKeys
+		expectStatus int
+		expectDetail string
+		expectOpc    string
+	}{
+		{
+			// OPc of TS 35.208 test set 1 is cached after the first derivation
+			name:         "OP only",
+			k:            "465b5ce8b199b49faa5f0a2ee238a6bc",
+			operatorKeys: &factory.OperatorKeys{Op: "cdc202d5123e20f62b6d676ac72cb318"},
+			expectStatus: 200,
+			expectOpc:    "cd63cb71954a9f4e48a5994e37a02baf",
+		},
+		{
+			// TOPc of TS 35.232 test set 1
+			name:         "TUAK with TOP only",
+			algorithmId:  "tuak",
+			k:            "abababababababababababababababab",
+			operatorKeys: &factory.OperatorKeys{Top: "5555555555555555555555555555555555555555555555555555555555555555"},
+			expectStatus: 200,
+			expectOpc:    "bd04d9530e87513c5d837ac2ad954623a8e2330c115305a73eb45d1f40cccbff",
+		},
+		{
+			name:         "TOPc is not taken as OP",
+			k:            "465b5ce8b199b49faa5f0a2ee238a6bc",
+			encTopcKey:   "cdc202d5123e20f62b6d676ac72cb318",
+			expectStatus: 403,
+			expectDetail: "neither OPc nor OP is provisioned",
+		},
+		{
+			name:         "Unsupported algorithm",
+			algorithmId:  "128-EIA3",
+			k:            "465b5ce8b199b49faa5f0a2ee238a6bc",
+			encOpcKey:    "cd63cb71954a9f4e48a5994e37a02baf",
+			expectStatus: 403,
+			expectDetail: "unsupported algorithmId [128-EIA3]",
+		},
+		{
+			name:         "Neither OPc nor OP",
+			k:            "465b5ce8b199b49faa5f0a2ee238a6bc",
+			expectStatus: 403,
+			expectDetail: "neither OPc nor OP is provisioned",
+		},
+		{
+			name:         "Malformed OP",
+			k:            "465b5ce8b199b49faa5f0a2ee238a6bc",
+			operatorKeys: &factory.OperatorKeys{Op: "cdc202"},
+			expectStatus: 403,
+			expectDetail: "no usable OPc or OP in authentication subscription",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			udm_context.GetSelf().OperatorKeys = tc.operatorKeys
+			queryRes := models.AuthenticationSubscription{
+				AuthenticationMethod:          models.AuthMethod__5_G_AKA,
+				EncPermanentKey:               tc.k,
+				SequenceNumber:                &models.SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+				AlgorithmId:                   tc.algorithmId,
+				EncOpcKey:                     tc.encOpcKey,
+				EncTopcKey:                    tc.encTopcKey,
+			}
+
+			gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/imsi-208930000000002/authentication-data/authentication-subscription").
+				Reply(200).
+				AddHeader("Content-Type", "application/json").
+				JSON(queryRes)
+
+			gock.New("http://127.0.0.4:8000").
+				Patch("/nudr-dr/v2/subscription-data/imsi-208930000000002/authentication-data/authentication-subscription").
+				Reply(204).
+				JSON(map[string]string{})
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAuthDataProcedure(c,
+				models.AuthenticationInfoRequest{ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org"}, ue.Supi)
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectDetail != "" {
+				var problem models.ProblemDetails
+				require.NoError(t, openapi.Deserialize(&problem, httpRecorder.Body.Bytes(), "application/json"))
+				require.Equal(t, tc.expectDetail, problem.Detail)
+			}
+			if tc.expectOpc != "" {
+				k, _ := hex.DecodeString(tc.k)
+				op, _ := hex.DecodeString(tc.operatorKeys.Op + tc.operatorKeys.Top)
+				opc, ok := ue.GetDerivedOpc(k, op)
+				require.True(t, ok)
+				require.Equal(t, tc.expectOpc, hex.EncodeToString(opc))
+			}
+		})
+	}
+}
+
+func TestGenerateAuthDataProcedureAuthMethod(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000003"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	testCases := []struct {
+		name           string
+		authMethod     models.AuthMethod
+		expectStatus   int
+		expectAuthType models.UdmUeauAuthType
+		expectAvType   models.AvType
+	}{
+		{
+			name:           "5G AKA",
+			authMethod:     models.AuthMethod__5_G_AKA,
+			expectStatus:   200,
+			expectAuthType: models.UdmUeauAuthType__5_G_AKA,
+			expectAvType:   models.AvType__5_G_HE_AKA,
+		},
+		{
+			name:           "EAP-AKA'",
+			authMethod:     models.AuthMethod_EAP_AKA_PRIME,
+			expectStatus:   200,
+			expectAuthType: models.UdmUeauAuthType_EAP_AKA_PRIME,
+			expectAvType:   models.AvType_EAP_AKA_PRIME,
+		},
+		{
+			name:           "Not provisioned",
+			expectStatus:   200,
+			expectAuthType: models.UdmUeauAuthType__5_G_AKA,
+			expectAvType:   models.AvType__5_G_HE_AKA,
+		},
+		{
+			name:         "EAP-TLS",
+			authMethod:   models.AuthMethod_EAP_TLS,
+			expectStatus: 403,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queryRes := models.AuthenticationSubscription{
+				AuthenticationMethod:          tc.authMethod,
+				EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+				SequenceNumber:                &models.SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+				EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+			}
+
+			gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/imsi-208930000000003/authentication-data/authentication-subscription").
+				Reply(200).
+				AddHeader("Content-Type", "application/json").
+				JSON(queryRes)
+
+			if tc.expectStatus == 200 {
+				gock.New("http://127.0.0.4:8000").
+					Patch("/nudr-dr/v2/subscription-data/imsi-208930000000003/authentication-data/authentication-subscription").
+					Reply(204).
+					JSON(map[string]string{})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAuthDataProcedure(c,
+				models.AuthenticationInfoRequest{ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org"}, ue.Supi)
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectStatus != 200 {
+				return
+			}
+			var res models.UdmUeauAuthenticationInfoResult
+			require.NoError(t, openapi.Deserialize(&res, httpRecorder.Body.Bytes(), "application/json"))
+			require.Equal(t, tc.expectAuthType, res.AuthType)
+			require.Equal(t, tc.expectAvType, res.AuthenticationVector.AvType)
+			if tc.expectAvType == models.AvType_EAP_AKA_PRIME {
+				require.Len(t, res.AuthenticationVector.CkPrime, 32)
+				require.Len(t, res.AuthenticationVector.IkPrime, 32)
+				require.Len(t, res.AuthenticationVector.Xres, 16)
+				require.Empty(t, res.AuthenticationVector.Kausf)
+			} else {
+				require.Len(t, res.AuthenticationVector.XresStar, 32)
+				require.Len(t, res.AuthenticationVector.Kausf, 64)
+			}
+		})
+	}
+}
+
+// RFC 5448 Appendix C test cases 1 and 2
+func TestGenerateAuthDataProcedureSqnManagement(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	require.NoError(t, util.InitSqnManagement(&factory.SqnManagement{
+		Profile:        util.SqnProfileNonTimeBased,
+		Delta:          0x1000,
+		FreshnessLimit: 0x1000,
+	}))
+	defer func() {
+		require.NoError(t, util.InitSqnManagement(nil))
+	}()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000009"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	k, _ := hex.DecodeString("465b5ce8b199b49faa5f0a2ee238a6bc")
+	opc, _ := hex.DecodeString("cd63cb71954a9f4e48a5994e37a02baf")
+	rand, _ := hex.DecodeString("23553cbe9637a89d218ae64dae47bf35")
+	// SQN_HE is SEQ 0x2000 with IND 1
+	sqnHe := "000000040001"
+
+	testCases := []struct {
+		name              string
+		sqnMs             string
+		expectPatchedSqn  string
+		expectLastIndexes map[string]int32
+	}{
+		{
+			name:              "IND allocated to the serving network",
+			expectPatchedSqn:  "000000040020",
+			expectLastIndexes: map[string]int32{"sn-a": 1, util.IndKey("5G:mnc093.mcc208.3gppnetwork.org"): 0},
+		},
+		{
+			name:              "Resynchronisation after out of order use keeps SQN_HE",
+			sqnMs:             "000000030002",
+			expectPatchedSqn:  "000000040020",
+			expectLastIndexes: map[string]int32{"sn-a": 1, util.IndKey("5G:mnc093.mcc208.3gppnetwork.org"): 0},
+		},
+		{
+			name:              "Resynchronisation with stale SQN_MS resets SQN_HE",
+			sqnMs:             "000000000002",
+			expectPatchedSqn:  "000000000020",
+			expectLastIndexes: map[string]int32{"sn-a": 1, util.IndKey("5G:mnc093.mcc208.3gppnetwork.org"): 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/imsi-208930000000009/authentication-data/authentication-subscription").
+				Reply(200).
+				AddHeader("Content-Type", "application/json").
+				JSON(models.AuthenticationSubscription{
+					AuthenticationMethod: models.AuthMethod__5_G_AKA,
+					EncPermanentKey:      hex.EncodeToString(k),
+					SequenceNumber: &models.SequenceNumber{
+						Sqn:         sqnHe,
+						IndLength:   5,
+						LastIndexes: map[string]int32{"sn-a": 1},
+					},
+					AuthenticationManagementField: "8000",
+					EncOpcKey:                     hex.EncodeToString(opc),
+				})
+
+			var patched []models.PatchItem
+			gock.New("http://127.0.0.4:8000").
+				Patch("/nudr-dr/v2/subscription-data/imsi-208930000000009/authentication-data/authentication-subscription").
+				AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+					return true, json.NewDecoder(req.Body).Decode(&patched)
+				}).
+				Reply(204)
+
+			authInfoRequest := models.AuthenticationInfoRequest{
+				ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+			}
+			if tc.sqnMs != "" {
+				sqnMs, _ := hex.DecodeString(tc.sqnMs)
+				auts, err := milenage.GenerateAUTS(opc, k, rand, sqnMs)
+				require.NoError(t, err)
+				authInfoRequest.ResynchronizationInfo = &models.ResynchronizationInfo{
+					Rand: hex.EncodeToString(rand),
+					Auts: hex.EncodeToString(auts),
+				}
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAuthDataProcedure(c, authInfoRequest, ue.Supi)
+			require.Equal(t, 200, httpRecorder.Code)
+
+			// the update is conditional on the SQN read
+			require.Len(t, patched, 2)
+			require.Equal(t, models.PatchItem{
+				Op:    models.PatchOperation_TEST,
+				Path:  "/sequenceNumber/sqn",
+				Value: sqnHe,
+			}, patched[0])
+			value, err := json.Marshal(patched[1].Value)
+			require.NoError(t, err)
+			var sequenceNumber models.SequenceNumber
+			require.NoError(t, json.Unmarshal(value, &sequenceNumber))
+			require.Equal(t, tc.expectPatchedSqn, sequenceNumber.Sqn)
+			require.Equal(t, models.SqnScheme_NON_TIME_BASED, sequenceNumber.SqnScheme)
+			require.Equal(t, int32(5), sequenceNumber.IndLength)
+			require.Equal(t, tc.expectLastIndexes, sequenceNumber.LastIndexes)
+		})
+	}
+}
+
+func TestGenerateAuthDataProcedureSqnConflict(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000010"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	testCases := []struct {
+		name           string
+		sqnAbsent      bool
+		conflicts      int
+		conflictStatus int
+		expectStatus   int
+	}{
+		{
+			name:           "Retry after concurrent update",
+			conflicts:      1,
+			conflictStatus: 409,
+			expectStatus:   200,
+		},
+		{
+			name:           "Retry after concurrent first allocation",
+			sqnAbsent:      true,
+			conflicts:      1,
+			conflictStatus: 412,
+			expectStatus:   200,
+		},
+		{
+			name:           "Give up after repeated conflicts",
+			conflicts:      maxSqnUpdateAttempts,
+			conflictStatus: 409,
+			expectStatus:   403,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tested []string
+			for attempt := 0; attempt <= tc.conflicts && attempt < maxSqnUpdateAttempts; attempt++ {
+				sequenceNumber := &models.SequenceNumber{Sqn: fmt.Sprintf("%012x", 0x23+attempt)}
+				if tc.sqnAbsent && attempt == 0 {
+					sequenceNumber = nil
+				}
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Get("/subscription-data/imsi-208930000000010/authentication-data/authentication-subscription").
+					Reply(200).
+					AddHeader("Content-Type", "application/json").
+					JSON(models.AuthenticationSubscription{
+						AuthenticationMethod:          models.AuthMethod__5_G_AKA,
+						EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+						SequenceNumber:                sequenceNumber,
+						AuthenticationManagementField: "8000",
+						EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+					})
+
+				status := 204
+				if attempt < tc.conflicts {
+					status = tc.conflictStatus
+				}
+				gock.New("http://127.0.0.4:8000").
+					Patch("/nudr-dr/v2/subscription-data/imsi-208930000000010/authentication-data/authentication-subscription").
+					AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+						var patched []models.PatchItem
+						if err := json.NewDecoder(req.Body).Decode(&patched); err != nil {
+							return false, err
+						}
+						require.Len(t, patched, 2)
+						require.Equal(t, models.PatchOperation_TEST, patched[0].Op)
+						tested = append(tested, fmt.Sprintf("%s=%v %s", patched[0].Path, patched[0].Value, patched[1].Op))
+						return true, nil
+					}).
+					Reply(status).
+					JSON(models.ProblemDetails{Status: int32(status)})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAuthDataProcedure(c, models.AuthenticationInfoRequest{
+				ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+			}, ue.Supi)
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			require.True(t, gock.IsDone())
+
+			// each attempt is conditional on the SQN read before it
+			require.Len(t, tested, min(tc.conflicts+1, maxSqnUpdateAttempts))
+			expectTested := make([]string, 0, len(tested))
+			for attempt := range tested {
+				if tc.sqnAbsent && attempt == 0 {
+					// the first allocation fails if another one stored a sequence number meanwhile
+					expectTested = append(expectTested, "/sequenceNumber=<nil> add")
+					continue
+				}
+				expectTested = append(expectTested, fmt.Sprintf("/sequenceNumber/sqn=%012x replace", 0x23+attempt))
+			}
+			require.Equal(t, expectTested, tested)
+		})
+	}
+}
+
+func TestGenerateAuthDataProcedureVectorPool(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+	testProcessor.vectorPool = newVectorPool(&factory.AuthVectorPool{Size: 3})
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000011"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	patched := []string{"000000000023"}
+	rands := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		sqnHe := patched[len(patched)-1]
+		if i == 4 {
+			// another UDM instance advanced SQN_HE, the pooled vector is stale
+			sqnHe = "000000000060"
+		}
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get("/subscription-data/imsi-208930000000011/authentication-data/authentication-subscription").
+			Reply(200).
+			AddHeader("Content-Type", "application/json").
+			JSON(models.AuthenticationSubscription{
+				AuthenticationMethod:          models.AuthMethod__5_G_AKA,
+				EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+				SequenceNumber:                &models.SequenceNumber{Sqn: sqnHe},
+				AuthenticationManagementField: "8000",
+				EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+			})
+		// a PATCH reserves the SQNs of a whole batch
+		if i%3 == 0 || i == 4 {
+			gock.New("http://127.0.0.4:8000").
+				Patch("/nudr-dr/v2/subscription-data/imsi-208930000000011/authentication-data/authentication-subscription").
+				AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+					var items []models.PatchItem
+					if err := json.NewDecoder(req.Body).Decode(&items); err != nil {
+						return false, err
+					}
+					stored := items[len(items)-1].Value.(map[string]interface{})
+					patched = append(patched, stored["sqn"].(string))
+					return true, nil
+				}).
+				Reply(204)
+		}
+
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GenerateAuthDataProcedure(c, models.AuthenticationInfoRequest{
+			ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		}, ue.Supi)
+		require.Equal(t, 200, httpRecorder.Code)
+
+		var result models.UdmUeauAuthenticationInfoResult
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &result))
+		require.False(t, rands[result.AuthenticationVector.Rand])
+		rands[result.AuthenticationVector.Rand] = true
+	}
+	require.True(t, gock.IsDone())
+	require.Equal(t, []string{"000000000023", "000000000026", "000000000029", "000000000063"}, patched)
+}
+
+func TestGenerateAuthDataProcedureSqnAudit(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000012"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	for i := 0; i < 2; i++ {
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get("/subscription-data/imsi-208930000000012/authentication-data/authentication-subscription").
+			Reply(200).
+			AddHeader("Content-Type", "application/json").
+			JSON(models.AuthenticationSubscription{
+				AuthenticationMethod:          models.AuthMethod__5_G_AKA,
+				EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+				SequenceNumber:                &models.SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+				EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+			})
+	}
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Patch("/subscription-data/imsi-208930000000012/authentication-data/authentication-subscription").
+		Reply(204)
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateAuthDataProcedure(c, models.AuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		AusfInstanceId:     "5a3f6c1e-8d2b-4c7a-9e0f-1b2c3d4e5f60",
+	}, ue.Supi)
+	require.Equal(t, 200, httpRecorder.Code)
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateAuthDataProcedure(c, models.AuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		AusfInstanceId:     "5a3f6c1e-8d2b-4c7a-9e0f-1b2c3d4e5f60",
+		ResynchronizationInfo: &models.ResynchronizationInfo{
+			Rand: "23553cbe9637a89d218ae64dae47bf35",
+			Auts: "0000000000000000000000000000",
+		},
+	}, ue.Supi)
+	require.Equal(t, 403, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.GetSqnAuditProcedure(c, ue.Supi, 0)
+	require.Equal(t, 200, httpRecorder.Code)
+
+	var records []udm_context.SqnAuditRecord
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &records))
+	require.Len(t, records, 2)
+	for _, record := range records {
+		require.Equal(t, ue.Supi, record.Supi)
+		require.Equal(t, "5G:mnc093.mcc208.3gppnetwork.org", record.ServingNetworkName)
+		require.Equal(t, "5a3f6c1e-8d2b-4c7a-9e0f-1b2c3d4e5f60", record.RequesterNfId)
+	}
+	require.Equal(t, udm_context.SqnAuditMacFailure, records[0].Outcome)
+	require.NotEmpty(t, records[0].SqnMs)
+	require.Equal(t, udm_context.SqnAuditIssued, records[1].Outcome)
+	require.Equal(t, "000000000023", records[1].OldSqn)
+	require.Equal(t, "000000000024", records[1].NewSqn)
+	require.Equal(t, []string{"000000000023"}, records[1].Sqns)
+}
+
+func TestDeleteAuthProcedure(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000007"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Put("/subscription-data/imsi-208930000000007/authentication-data/authentication-status").
+		Reply(204)
+
+	timeStamp := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC)
+	authEvent := models.AuthEvent{
+		NfInstanceId:       "b5f3a0a4-1d0e-4f0f-9b5e-2f3f5e6c7d8a",
+		Success:            true,
+		TimeStamp:          &timeStamp,
+		AuthType:           models.UdmUeauAuthType__5_G_AKA,
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.ConfirmAuthDataProcedure(c, authEvent, ue.Supi)
+	require.Equal(t, 201, httpRecorder.Code)
+	authEventId := path.Base(httpRecorder.Header().Get("Location"))
+	require.NotEmpty(t, authEventId)
+
+	// the UDR stores the timestamp with millisecond precision
+	storedTimeStamp := timeStamp.Truncate(time.Millisecond)
+	storedAuthEvent := authEvent
+	storedAuthEvent.TimeStamp = &storedTimeStamp
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/imsi-208930000000007/authentication-data/authentication-status").
+		Reply(200).
+		JSON(storedAuthEvent)
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeleteAuthProcedure(c, ue.Supi, "unknown")
+	require.Equal(t, 404, httpRecorder.Code)
+
+	// the result is found in the UDR even if this instance lost the UE context
+	restartedUe := new(udm_context.UdmUeContext)
+	restartedUe.Init()
+	restartedUe.Supi = ue.Supi
+	restartedUe.UdrUri = ue.UdrUri
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, restartedUe)
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/imsi-208930000000007/authentication-data/authentication-status").
+		Reply(200).
+		JSON(storedAuthEvent)
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Delete("/subscription-data/imsi-208930000000007/authentication-data/authentication-status").
+		Reply(204)
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeleteAuthProcedure(c, ue.Supi, authEventId)
+	require.Equal(t, 204, c.Writer.Status())
+	require.True(t, gock.IsDone())
+
+	// the result is removed, a second deletion is rejected
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/imsi-208930000000007/authentication-data/authentication-status").
+		Reply(404)
+
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeleteAuthProcedure(c, ue.Supi, authEventId)
+	require.Equal(t, 404, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+}
+
+func TestDecryptAuthSubscriptionKeys(t *testing.T) {
+	testProcessor, err := NewProcessor(nil)
+	require.NoError(t, err)
+
+	kek, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	require.NoError(t, util.InitKeyEncryptionKeys([]factory.KeyEncryptionKey{
+		{KeyId: "kek-1", Algorithm: factory.KekAlgorithmAesKw, Key: hex.EncodeToString(kek)},
+		{KeyId: "kek-2", Algorithm: factory.KekAlgorithmAes256Gcm, Key: hex.EncodeToString(kek)},
+	}))
+	defer func() {
+		require.NoError(t, util.InitKeyEncryptionKeys(nil))
+		util.SetAllowPlaintextKeys(false)
+	}()
+
+	authSubs := &models.AuthenticationSubscription{
+		EncPermanentKey:       "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7",
+		ProtectionParameterId: "kek-1",
+	}
+	require.NoError(t, testProcessor.decryptAuthSubscriptionKeys("imsi-208930000000001", authSubs))
+	require.Equal(t, "00112233445566778899aabbccddeeff", authSubs.EncPermanentKey)
+
+	// AES-256-GCM keys are bound to the SUPI and field they were encrypted for
+	block, err := aes.NewCipher(kek)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	plaintextK, _ := hex.DecodeString("8baf473f2f8fd09487cccbd7097c6862")
+	encK := hex.EncodeToString(aead.Seal(nonce, nonce, plaintextK,
+		[]byte("imsi-208930000000001|encPermanentKey")))
+
+	authSubs = &models.AuthenticationSubscription{EncPermanentKey: encK, ProtectionParameterId: "kek-2"}
+	require.NoError(t, testProcessor.decryptAuthSubscriptionKeys("imsi-208930000000001", authSubs))
+	require.Equal(t, "8baf473f2f8fd09487cccbd7097c6862", authSubs.EncPermanentKey)
+	authSubs = &models.AuthenticationSubscription{EncPermanentKey: encK, ProtectionParameterId: "kek-2"}
+	require.Error(t, testProcessor.decryptAuthSubscriptionKeys("imsi-208930000000002", authSubs))
+	authSubs = &models.AuthenticationSubscription{EncOpcKey: encK, ProtectionParameterId: "kek-2"}
+	require.Error(t, testProcessor.decryptAuthSubscriptionKeys("imsi-208930000000001", authSubs))
+
+	// plaintext keys are rejected while KEKs are configured
+	authSubs = &models.AuthenticationSubscription{
+		EncPermanentKey: "8baf473f2f8fd09487cccbd7097c6862",
+		EncOpcKey:       "8e27b6af0e692e750f32667a3b14605d",
+	}
+	require.Error(t, testProcessor.decryptAuthSubscriptionKeys("imsi-208930000000001", authSubs))
+
+	util.SetAllowPlaintextKeys(true)
+	require.NoError(t, testProcessor.decryptAuthSubscriptionKeys("imsi-208930000000001", authSubs))
+	require.Equal(t, "8baf473f2f8fd09487cccbd7097c6862", authSubs.EncPermanentKey)
+}
+
+func TestSqnAdd(t *testing.T) {
+	testCases := []struct {
+		sqn    string
+		n      int64
+		expect string
+	}{
+		{"000000000023", 3, "000000000026"},
+		{"ffffffffffff", 1, "000000000000"},
+		// re-synchronisation without SQN management adds IND + 1 to SQN_MS
+		{"ffffffffffe0", ind + 1, "000000000001"},
+	}
+
+	for _, tc := range testCases {
+		sqn, _ := hex.DecodeString(tc.sqn)
+		require.Equal(t, tc.expect, hex.EncodeToString(sqnAdd(sqn, tc.n)))
+	}
+}
+
+func TestDeriveCkPrimeIkPrime(t *testing.T) {
+	ck, _ := hex.DecodeString("5349fbe098649f948f5d2e973a81c00f")
+	ik, _ := hex.DecodeString("9744871ad32bf9bbd1dd5ce54e3e2e5a")
+	autn, _ := hex.DecodeString("bb52e91c747ac3ab2a5c23d15ee351d5")
+
+	testCases := []struct {
+		networkName string
+		ckPrime     string
+		ikPrime     string
+	}{
+		{
+			networkName: "WLAN",
+			ckPrime:     "0093962d0dd84aa5684b045c9edffa04",
+			ikPrime:     "ccfc230ca74fcc96c0a5d61164f5a76c",
+		},
+		{
+			networkName: "HRPD",
+			ckPrime:     "3820f0277fa5f77732b1fb1d90c1a0da",
+			ikPrime:     "db94a0ab557ef6c9ab48619ca05b9a9f",
+		},
+	}
+
+	for _, tc := range testCases {
+		ckPrime, ikPrime, err := deriveCkPrimeIkPrime(ck, ik, tc.networkName, autn[:6])
+		require.NoError(t, err)
+		require.Equal(t, tc.ckPrime, hex.EncodeToString(ckPrime))
+		require.Equal(t, tc.ikPrime, hex.EncodeToString(ikPrime))
+	}
+}
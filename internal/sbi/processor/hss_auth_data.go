@@ -0,0 +1,246 @@
+package processor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/util/metrics/sbi"
+	"github.com/free5gc/util/ueauth"
+)
+
+const (
+	// KASME derivation, TS 33.401 Annex A.2
+	fcForKasmeDerivation = "10"
+	// numOfRequestedVectors range, TS 29.503 clause 6.3.6.3.2
+	maxNumOfRequestedVectors = 32
+	// AMF separation bit (bit 0 of the AMF) of E-UTRAN vectors, TS 33.401 clause 6.1.2
+	amfSeparationBit = 0x80
+)
+
+// HssAuthenticationInfoResult with the HssAuthenticationVectors array, which
+// the generated model leaves empty. Items are AvEpsAka, AvImsGbaEapAka or
+// AvEapAkaPrime.
+type HssAuthenticationInfoResult struct {
+	SupportedFeatures        string        `json:"supportedFeatures,omitempty"`
+	HssAuthenticationVectors []interface{} `json:"hssAuthenticationVectors"`
+}
+
+var hssAuthTypeInUri = map[models.HssAuthTypeInUri]models.HssAuthType{
+	models.HssAuthTypeInUri_EPS_AKA:       models.HssAuthType_EPS_AKA,
+	models.HssAuthTypeInUri_EAP_AKA:       models.HssAuthType_EAP_AKA,
+	models.HssAuthTypeInUri_EAP_AKA_PRIME: models.HssAuthType_EAP_AKA_PRIME,
+	models.HssAuthTypeInUri_IMS_AKA:       models.HssAuthType_IMS_AKA,
+	models.HssAuthTypeInUri_GBA_AKA:       models.HssAuthType_GBA_AKA,
+}
+
+// plmnIdToSnId encodes the serving network identity (MCC, MNC) as in the
+// PLMN identity of TS 24.301 clause 9.9.3.32.
+func plmnIdToSnId(plmnId *models.PlmnId) ([]byte, error) {
+	if plmnId == nil {
+		return nil, fmt.Errorf("servingNetworkId is required")
+	}
+	mnc := plmnId.Mnc
+	if len(mnc) == 2 {
+		mnc += "f"
+	}
+	if len(plmnId.Mcc) != 3 || len(mnc) != 3 {
+		return nil, fmt.Errorf("invalid servingNetworkId [%s-%s]", plmnId.Mcc, plmnId.Mnc)
+	}
+	return hex.DecodeString(string([]byte{
+		plmnId.Mcc[1], plmnId.Mcc[0],
+		mnc[2], plmnId.Mcc[2],
+		mnc[1], mnc[0],
+	}))
+}
+
+// deriveKasme derives KASME for EPS AKA, TS 33.401 Annex A.2.
+// amfSeparationBitSet tells whether the separation bit of the hex encoded amf
+// is set
+func amfSeparationBitSet(amf string) bool {
+	b, err := hex.DecodeString(amf)
+	return err == nil && len(b) == 2 && b[0]&amfSeparationBit != 0
+}
+
+func deriveKasme(ck, ik, snId, sqnXorAk []byte) ([]byte, error) {
+	key := append(append([]byte{}, ck...), ik...)
+	return ueauth.GetKDFValue(key, fcForKasmeDerivation, snId, ueauth.KDFLen(snId), sqnXorAk, ueauth.KDFLen(sqnXorAk))
+}
+
+func (p *Processor) GenerateAvProcedure(
+	c *gin.Context,
+	authInfoRequest models.HssAuthenticationInfoRequest,
+	supi string,
+	authTypeInUri string,
+) {
+	hssAuthType, ok := hssAuthTypeInUri[models.HssAuthTypeInUri(authTypeInUri)]
+	if !ok || (authInfoRequest.HssAuthType != "" && authInfoRequest.HssAuthType != hssAuthType) {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "INVALID_HSS_AUTH_TYPE",
+			Detail: fmt.Sprintf("hssAuthType [%s] does not match [%s]", authInfoRequest.HssAuthType, authTypeInUri),
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	numVectors := int(authInfoRequest.NumOfRequestedVectors)
+	if numVectors < 1 || numVectors > maxNumOfRequestedVectors {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "INVALID_NUMBER_OF_VECTORS",
+			Detail: fmt.Sprintf("numOfRequestedVectors should be 1 to %d", maxNumOfRequestedVectors),
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var snId []byte
+	var err error
+	if hssAuthType == models.HssAuthType_EPS_AKA {
+		if snId, err = plmnIdToSnId(authInfoRequest.ServingNetworkId); err != nil {
+			problemDetails := &models.ProblemDetails{
+				Status: http.StatusBadRequest,
+				Cause:  "MANDATORY_IE_INCORRECT",
+				Detail: err.Error(),
+			}
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+	}
+	if !p.authorizeServingPlmn(c, authInfoRequest.ServingNetworkId) {
+		return
+	}
+	if hssAuthType == models.HssAuthType_EAP_AKA_PRIME && authInfoRequest.AnId == "" {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_MISSING",
+			Detail: "anId is required for EAP-AKA'",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	supi, err = p.Context().SuciToSupi(supi)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if !p.checkAuthLockout(c, supi) {
+		return
+	}
+
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubs, ok := p.queryAuthSubscription(ctx, c, supi)
+	if !ok {
+		return
+	}
+	// the UE only takes vectors with the separation bit set for E-UTRAN
+	if hssAuthType == models.HssAuthType_EPS_AKA && !amfSeparationBitSet(authSubs.AuthenticationManagementField) {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: fmt.Sprintf("AMF [%s] has the separation bit cleared", authSubs.AuthenticationManagementField),
+		}
+		logger.UeauLog.Errorf("EPS AKA rejected for supi=[%s]: %s", supi, problemDetails.Detail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	// the serving network is known for EPS AKA and EAP-AKA' only
+	audit := &udm_context.SqnAuditRecord{}
+	indKey := string(hssAuthType)
+	if authInfoRequest.ServingNetworkId != nil {
+		indKey = authInfoRequest.ServingNetworkId.Mcc + authInfoRequest.ServingNetworkId.Mnc
+		audit.ServingNetworkName = indKey
+	} else if authInfoRequest.AnId != "" {
+		indKey = string(authInfoRequest.AnId)
+		audit.ServingNetworkName = indKey
+	}
+	vectors, ok := p.generateAuthVectors(ctx, c, supi, authSubs, indKey, numVectors,
+		authInfoRequest.ResynchronizationInfo, audit)
+	if !ok {
+		return
+	}
+
+	response := &HssAuthenticationInfoResult{
+		HssAuthenticationVectors: make([]interface{}, 0, len(vectors)),
+	}
+	for _, vector := range vectors {
+		switch hssAuthType {
+		case models.HssAuthType_EPS_AKA:
+			kasme, err := deriveKasme(vector.Ck, vector.Ik, snId, vector.SqnXorAk)
+			if err != nil {
+				problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+				logger.UeauLog.Errorf("Derive KASME err: %+v", err)
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+				c.JSON(int(problemDetails.Status), problemDetails)
+				return
+			}
+			response.HssAuthenticationVectors = append(response.HssAuthenticationVectors, models.AvEpsAka{
+				AvType: models.HssAvType_EPS_AKA,
+				Rand:   hex.EncodeToString(vector.Rand),
+				Xres:   hex.EncodeToString(vector.Xres),
+				Autn:   hex.EncodeToString(vector.Autn),
+				Kasme:  hex.EncodeToString(kasme),
+			})
+		case models.HssAuthType_EAP_AKA_PRIME:
+			ckPrime, ikPrime, err := deriveCkPrimeIkPrime(vector.Ck, vector.Ik, string(authInfoRequest.AnId),
+				vector.SqnXorAk)
+			if err != nil {
+				problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+				logger.UeauLog.Errorf("Derive CK' and IK' err: %+v", err)
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+				c.JSON(int(problemDetails.Status), problemDetails)
+				return
+			}
+			response.HssAuthenticationVectors = append(response.HssAuthenticationVectors, models.AvEapAkaPrime{
+				AvType:  models.AvType_EAP_AKA_PRIME,
+				Rand:    hex.EncodeToString(vector.Rand),
+				Xres:    hex.EncodeToString(vector.Xres),
+				Autn:    hex.EncodeToString(vector.Autn),
+				CkPrime: hex.EncodeToString(ckPrime),
+				IkPrime: hex.EncodeToString(ikPrime),
+			})
+		default: // IMS AKA, EAP-AKA and GBA AKA use the plain quintet
+			response.HssAuthenticationVectors = append(response.HssAuthenticationVectors, models.AvImsGbaEapAka{
+				AvType: models.HssAvType(hssAuthType),
+				Rand:   hex.EncodeToString(vector.Rand),
+				Xres:   hex.EncodeToString(vector.Xres),
+				Autn:   hex.EncodeToString(vector.Autn),
+				Ck:     hex.EncodeToString(vector.Ck),
+				Ik:     hex.EncodeToString(vector.Ik),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
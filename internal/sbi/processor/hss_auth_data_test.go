@@ -0,0 +1,193 @@
+package processor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+func TestPlmnIdToSnId(t *testing.T) {
+	testCases := []struct {
+		plmnId models.PlmnId
+		snId   string
+	}{
+		{plmnId: models.PlmnId{Mcc: "208", Mnc: "93"}, snId: "02f839"},
+		{plmnId: models.PlmnId{Mcc: "310", Mnc: "410"}, snId: "130014"},
+	}
+	for _, tc := range testCases {
+		snId, err := plmnIdToSnId(&tc.plmnId)
+		require.NoError(t, err)
+		require.Equal(t, tc.snId, hex.EncodeToString(snId))
+	}
+
+	_, err := plmnIdToSnId(&models.PlmnId{Mcc: "20", Mnc: "93"})
+	require.Error(t, err)
+	_, err = plmnIdToSnId(nil)
+	require.Error(t, err)
+}
+
+func TestGenerateAvProcedure(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000004"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	udm_context.GetSelf().AllowedPlmnList = []models.PlmnId{{Mcc: "208", Mnc: "93"}}
+	defer func() { udm_context.GetSelf().AllowedPlmnList = nil }()
+
+	testCases := []struct {
+		name          string
+		authTypeInUri string
+		request       models.HssAuthenticationInfoRequest
+		amf           string // AMF of the subscription read from UDR
+		expectStatus  int
+		expectVectors []string
+	}{
+		{
+			name:          "EPS AKA",
+			authTypeInUri: "eps-aka",
+			request: models.HssAuthenticationInfoRequest{
+				HssAuthType:           models.HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 2,
+				ServingNetworkId:      &models.PlmnId{Mcc: "208", Mnc: "93"},
+			},
+			amf:           "8000",
+			expectStatus:  200,
+			expectVectors: []string{"avType", "rand", "xres", "autn", "kasme"},
+		},
+		{
+			name:          "IMS AKA",
+			authTypeInUri: "ims-aka",
+			request: models.HssAuthenticationInfoRequest{
+				HssAuthType:           models.HssAuthType_IMS_AKA,
+				NumOfRequestedVectors: 1,
+			},
+			amf:           "8000",
+			expectStatus:  200,
+			expectVectors: []string{"avType", "rand", "xres", "autn", "ck", "ik"},
+		},
+		{
+			name:          "EPS AKA with the AMF separation bit cleared",
+			authTypeInUri: "eps-aka",
+			request: models.HssAuthenticationInfoRequest{
+				HssAuthType:           models.HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+				ServingNetworkId:      &models.PlmnId{Mcc: "208", Mnc: "93"},
+			},
+			amf:          "0000",
+			expectStatus: 403,
+		},
+		{
+			name:          "EPS AKA without serving network",
+			authTypeInUri: "eps-aka",
+			request: models.HssAuthenticationInfoRequest{
+				HssAuthType:           models.HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+			},
+			expectStatus: 400,
+		},
+		{
+			name:          "EPS AKA from a serving network not allowed",
+			authTypeInUri: "eps-aka",
+			request: models.HssAuthenticationInfoRequest{
+				HssAuthType:           models.HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+				ServingNetworkId:      &models.PlmnId{Mcc: "001", Mnc: "01"},
+			},
+			expectStatus: 403,
+		},
+		{
+			name:          "Auth type mismatch",
+			authTypeInUri: "ims-aka",
+			request: models.HssAuthenticationInfoRequest{
+				HssAuthType:           models.HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+			},
+			expectStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.amf != "" {
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Get("/subscription-data/imsi-208930000000004/authentication-data/authentication-subscription").
+					Reply(200).
+					AddHeader("Content-Type", "application/json").
+					JSON(models.AuthenticationSubscription{
+						EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+						SequenceNumber:                &models.SequenceNumber{Sqn: "000000000023"},
+						AuthenticationManagementField: tc.amf,
+						EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+					})
+			}
+			if tc.expectStatus == 200 {
+				gock.New("http://127.0.0.4:8000").
+					Patch("/nudr-dr/v2/subscription-data/imsi-208930000000004/authentication-data/authentication-subscription").
+					Reply(204).
+					JSON(map[string]string{})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAvProcedure(c, tc.request, ue.Supi, tc.authTypeInUri)
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			require.True(t, gock.IsDone())
+			if tc.expectStatus != 200 {
+				return
+			}
+			var res struct {
+				HssAuthenticationVectors []map[string]string `json:"hssAuthenticationVectors"`
+			}
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+			require.Len(t, res.HssAuthenticationVectors, int(tc.request.NumOfRequestedVectors))
+			for _, av := range res.HssAuthenticationVectors {
+				require.Len(t, av, len(tc.expectVectors))
+				for _, key := range tc.expectVectors {
+					require.NotEmpty(t, av[key])
+				}
+				require.Equal(t, string(tc.request.HssAuthType), av["avType"])
+				if tc.request.HssAuthType == models.HssAuthType_EPS_AKA {
+					// AUTN is SQN xor AK || AMF || MAC-A, with the AMF separation bit set
+					autn, err := hex.DecodeString(av["autn"])
+					require.NoError(t, err)
+					require.Len(t, autn, 16)
+					require.NotZero(t, autn[6]&amfSeparationBit)
+				}
+			}
+			if len(res.HssAuthenticationVectors) > 1 {
+				require.NotEqual(t, res.HssAuthenticationVectors[0]["autn"], res.HssAuthenticationVectors[1]["autn"])
+			}
+		})
+	}
+}
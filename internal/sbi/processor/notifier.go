@@ -1,6 +1,8 @@
 package processor
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/free5gc/openapi"
@@ -15,6 +17,16 @@ func (p *Processor) DataChangeNotificationProcedure(c *gin.Context,
 	notifyItems []models.NotifyItem,
 	supi string,
 ) {
+	if p.vectorPool != nil {
+		for _, notifyItem := range notifyItems {
+			// pooled vectors were generated with the previous authentication subscription
+			if strings.Contains(notifyItem.ResourceId, "/authentication-data/") {
+				p.vectorPool.flush(supi)
+				break
+			}
+		}
+	}
+
 	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDM_SDM, models.NrfNfManagementNfType_UDM)
 	if err != nil {
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
@@ -3,6 +3,7 @@ package processor
 import (
 	"github.com/free5gc/udm/internal/sbi/consumer"
 	"github.com/free5gc/udm/pkg/app"
+	"github.com/free5gc/udm/pkg/factory"
 )
 
 type ProcessorUdm interface {
@@ -13,11 +14,18 @@ type ProcessorUdm interface {
 
 type Processor struct {
 	ProcessorUdm
+
+	vectorPool  *vectorPool
+	authLockout *authLockout
 }
 
 func NewProcessor(udm ProcessorUdm) (*Processor, error) {
 	p := &Processor{
 		ProcessorUdm: udm,
 	}
+	if cfg := factory.UdmConfig; cfg != nil {
+		p.vectorPool = newVectorPool(cfg.GetAuthVectorPool())
+		p.authLockout = newAuthLockout(cfg.GetAuthLockout())
+	}
 	return p, nil
 }
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/internal/util"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+// Relay Service Code is 24 bits, TS 24.554 clause 11.2.4
+const maxRelayServiceCode = 0xFFFFFF
+
+// ProSeAuthenticationInfoResult with the ProseAuthenticationVectors array,
+// which the generated model leaves empty.
+type ProSeAuthenticationInfoResult struct {
+	AuthType                   models.UdmUeauAuthType `json:"authType"`
+	ProseAuthenticationVectors []models.AvEapAkaPrime `json:"proseAuthenticationVectors,omitempty"`
+	Supi                       string                 `json:"supi,omitempty"`
+	SupportedFeatures          string                 `json:"supportedFeatures,omitempty"`
+}
+
+// GenerateProseAvProcedure returns the EAP-AKA' vector for the authentication
+// of a 5G ProSe Remote UE over a UE-to-Network relay, TS 33.503 clause 6.3.3.3.
+// The AUSF derives KAUSF_P and then CP-PRUK from CK' and IK', so these are
+// bound to the serving network name of the request.
+func (p *Processor) GenerateProseAvProcedure(
+	c *gin.Context,
+	authInfoRequest models.ProSeAuthenticationInfoRequest,
+	supiOrSuci string,
+) {
+	if authInfoRequest.ServingNetworkName == "" ||
+		authInfoRequest.RelayServiceCode < 0 || authInfoRequest.RelayServiceCode > maxRelayServiceCode {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: fmt.Sprintf("invalid servingNetworkName [%s] or relayServiceCode [%d]",
+				authInfoRequest.ServingNetworkName, authInfoRequest.RelayServiceCode),
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	if !p.authorizeServingNetwork(c, authInfoRequest.ServingNetworkName) {
+		return
+	}
+
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	supi, err := p.Context().SuciToSupi(supiOrSuci)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if !p.checkAuthLockout(c, supi) {
+		return
+	}
+
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubs, ok := p.queryAuthSubscription(ctx, c, supi)
+	if !ok {
+		return
+	}
+	// the request carries no ausfInstanceId, the AUSF is the NF its access token was granted to
+	ausfInstanceId := c.GetString(util.OAuth2SubjectCtxKey)
+	indKey := sqnIndKey(authInfoRequest.ServingNetworkName, ausfInstanceId)
+	vectors, ok := p.generateAuthVectors(ctx, c, supi, authSubs, indKey, 1,
+		authInfoRequest.ResynchronizationInfo, &udm_context.SqnAuditRecord{
+			ServingNetworkName: authInfoRequest.ServingNetworkName,
+			RequesterNfId:      ausfInstanceId,
+		})
+	if !ok {
+		return
+	}
+
+	ckPrime, ikPrime, err := deriveCkPrimeIkPrime(vectors[0].Ck, vectors[0].Ik,
+		authInfoRequest.ServingNetworkName, vectors[0].SqnXorAk)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		logger.UeauLog.Errorf("Derive CK' and IK' err: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	response := &ProSeAuthenticationInfoResult{
+		AuthType: models.UdmUeauAuthType_EAP_AKA_PRIME,
+		ProseAuthenticationVectors: []models.AvEapAkaPrime{
+			{
+				AvType:  models.AvType_EAP_AKA_PRIME,
+				Rand:    hex.EncodeToString(vectors[0].Rand),
+				Xres:    hex.EncodeToString(vectors[0].Xres),
+				Autn:    hex.EncodeToString(vectors[0].Autn),
+				CkPrime: hex.EncodeToString(ckPrime),
+				IkPrime: hex.EncodeToString(ikPrime),
+			},
+		},
+		Supi: supi,
+	}
+	c.JSON(http.StatusOK, response)
+}
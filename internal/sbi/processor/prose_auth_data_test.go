@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	"github.com/free5gc/udm/internal/util"
+	"github.com/free5gc/udm/pkg/factory"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+func TestGenerateProseAvProcedure(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000006"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/imsi-208930000000006/authentication-data/authentication-subscription").
+		Reply(200).
+		AddHeader("Content-Type", "application/json").
+		JSON(models.AuthenticationSubscription{
+			EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+			SequenceNumber:                &models.SequenceNumber{Sqn: "000000000023"},
+			AuthenticationManagementField: "8000",
+			EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+		})
+
+	gock.New("http://127.0.0.4:8000").
+		Patch("/nudr-dr/v2/subscription-data/imsi-208930000000006/authentication-data/authentication-subscription").
+		Reply(204).
+		JSON(map[string]string{})
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateProseAvProcedure(c, models.ProSeAuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		RelayServiceCode:   1,
+	}, ue.Supi)
+
+	require.Equal(t, 200, httpRecorder.Code)
+	var res ProSeAuthenticationInfoResult
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+	require.Equal(t, models.UdmUeauAuthType_EAP_AKA_PRIME, res.AuthType)
+	require.Equal(t, ue.Supi, res.Supi)
+	require.Len(t, res.ProseAuthenticationVectors, 1)
+	require.Len(t, res.ProseAuthenticationVectors[0].CkPrime, 32)
+	require.Len(t, res.ProseAuthenticationVectors[0].IkPrime, 32)
+
+	// Relay Service Code beyond 24 bits
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateProseAvProcedure(c, models.ProSeAuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		RelayServiceCode:   0x1000000,
+	}, ue.Supi)
+	require.Equal(t, 400, httpRecorder.Code)
+}
+
+func TestGenerateProseAvProcedureIndByAusf(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	require.NoError(t, util.InitSqnManagement(&factory.SqnManagement{
+		Profile:       util.SqnProfileNonTimeBased,
+		IndAllocation: factory.SqnIndByAusf,
+	}))
+	defer func() {
+		require.NoError(t, util.InitSqnManagement(nil))
+	}()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000015"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/imsi-208930000000015/authentication-data/authentication-subscription").
+		Reply(200).
+		AddHeader("Content-Type", "application/json").
+		JSON(models.AuthenticationSubscription{
+			EncPermanentKey:               "465b5ce8b199b49faa5f0a2ee238a6bc",
+			SequenceNumber:                &models.SequenceNumber{Sqn: "000000000020"},
+			AuthenticationManagementField: "8000",
+			EncOpcKey:                     "cd63cb71954a9f4e48a5994e37a02baf",
+		})
+
+	var patched []struct {
+		Op    models.PatchOperation `json:"op"`
+		Value json.RawMessage       `json:"value"`
+	}
+	gock.New("http://127.0.0.4:8000").
+		Patch("/nudr-dr/v2/subscription-data/imsi-208930000000015/authentication-data/authentication-subscription").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return true, json.NewDecoder(req.Body).Decode(&patched)
+		}).
+		Reply(204)
+
+	// the IND is allocated to the AUSF the access token was granted to
+	const ausfInstanceId = "5a3f6c1e-8d2b-4c7a-9e0f-1b2c3d4e5f60"
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	c.Set(util.OAuth2SubjectCtxKey, ausfInstanceId)
+	testProcessor.GenerateProseAvProcedure(c, models.ProSeAuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		RelayServiceCode:   1,
+	}, ue.Supi)
+
+	require.Equal(t, 200, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+	require.Len(t, patched, 2)
+	var sequenceNumber models.SequenceNumber
+	require.NoError(t, json.Unmarshal(patched[1].Value, &sequenceNumber))
+	require.Contains(t, sequenceNumber.LastIndexes, util.IndKey(ausfInstanceId))
+	require.NotContains(t, sequenceNumber.LastIndexes, util.IndKey("5G:mnc093.mcc208.3gppnetwork.org"))
+
+	records := udm_context.GetSelf().SqnAuditRecords(ue.Supi, 0)
+	require.NotEmpty(t, records)
+	require.Equal(t, ausfInstanceId, records[len(records)-1].RequesterNfId)
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+// GetRgAuthDataProcedure tells the AUSF whether the FN-RG authenticated by the
+// wireline access network is accepted by 5GC, TS 33.501 clause 7B.7. This is
+// the case only if the subscription allows the wireline authentication.
+func (p *Processor) GetRgAuthDataProcedure(
+	c *gin.Context,
+	supiOrSuci string,
+	authenticatedInd bool,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	supi, err := p.Context().SuciToSupi(supiOrSuci)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	authSubs, ok := p.queryAuthSubscriptionData(ctx, c, supi)
+	if !ok {
+		return
+	}
+
+	response := &models.UdmUeauRgAuthCtx{
+		AuthInd: authenticatedInd && authSubs.RgAuthenticationInd,
+		Supi:    supi,
+	}
+	if authenticatedInd && !authSubs.RgAuthenticationInd {
+		logger.UeauLog.Warnf("Wireline authentication of [%s] is not allowed by subscription", supi)
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+func TestGetRgAuthDataProcedure(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000008"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	testCases := []struct {
+		name                string
+		authenticatedInd    bool
+		rgAuthenticationInd bool
+		udrStatus           int
+		expectStatus        int
+		expectAuthInd       bool
+	}{
+		{
+			name:                "Authenticated by wireline network",
+			authenticatedInd:    true,
+			rgAuthenticationInd: true,
+			udrStatus:           200,
+			expectStatus:        200,
+			expectAuthInd:       true,
+		},
+		{
+			name:                "Not allowed by subscription",
+			authenticatedInd:    true,
+			rgAuthenticationInd: false,
+			udrStatus:           200,
+			expectStatus:        200,
+			expectAuthInd:       false,
+		},
+		{
+			name:                "Not authenticated",
+			authenticatedInd:    false,
+			rgAuthenticationInd: true,
+			udrStatus:           200,
+			expectStatus:        200,
+			expectAuthInd:       false,
+		},
+		{
+			name:             "Unknown subscriber",
+			authenticatedInd: true,
+			udrStatus:        404,
+			expectStatus:     404,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			udrReply := gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/imsi-208930000000008/authentication-data/authentication-subscription").
+				Reply(tc.udrStatus).
+				AddHeader("Content-Type", "application/json")
+			if tc.udrStatus == 200 {
+				udrReply.JSON(models.AuthenticationSubscription{
+					AuthenticationMethod: models.AuthMethod__5_G_AKA,
+					RgAuthenticationInd:  tc.rgAuthenticationInd,
+				})
+			} else {
+				udrReply.JSON(models.ProblemDetails{Status: int32(tc.udrStatus), Cause: "USER_NOT_FOUND"})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GetRgAuthDataProcedure(c, ue.Supi, tc.authenticatedInd)
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectStatus != 200 {
+				return
+			}
+			var rgAuthCtx models.UdmUeauRgAuthCtx
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &rgAuthCtx))
+			require.Equal(t, tc.expectAuthInd, rgAuthCtx.AuthInd)
+			require.Equal(t, ue.Supi, rgAuthCtx.Supi)
+		})
+	}
+}
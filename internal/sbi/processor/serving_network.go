@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/internal/util"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+// servingNetworkNameRegexp matches the serving network name of TS 24.501
+// clause 9.12.1, with the NID of an SNPN optionally appended.
+var servingNetworkNameRegexp = regexp.MustCompile(
+	`^5G:mnc([0-9]{3})\.mcc([0-9]{3})\.3gppnetwork\.org(:[0-9A-Fa-f]{11})?$`)
+
+// authorizeServingNetwork checks the format of servingNetworkName and that
+// its PLMN is in the allowed PLMN list, if configured. On failure the error
+// response has been written.
+func (p *Processor) authorizeServingNetwork(c *gin.Context, servingNetworkName string) bool {
+	match := servingNetworkNameRegexp.FindStringSubmatch(servingNetworkName)
+	if match == nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: fmt.Sprintf("invalid servingNetworkName [%s]", servingNetworkName),
+		}
+
+		logger.UeauLog.Errorln(problemDetails.Detail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return false
+	}
+
+	if p.plmnAllowed(match[2], match[1]) {
+		return true
+	}
+
+	problemDetails := &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "SERVING_NETWORK_NOT_AUTHORIZED",
+		Detail: fmt.Sprintf("serving network [%s] is not allowed", servingNetworkName),
+	}
+
+	logger.UeauLog.Warnln(problemDetails.Detail)
+	c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+	c.JSON(int(problemDetails.Status), problemDetails)
+	return false
+}
+
+// authorizeServingPlmn checks that servingNetworkId is in the allowed PLMN
+// list, if configured. On failure the error response has been written.
+func (p *Processor) authorizeServingPlmn(c *gin.Context, servingNetworkId *models.PlmnId) bool {
+	if servingNetworkId == nil || p.plmnAllowed(servingNetworkId.Mcc, servingNetworkId.Mnc) {
+		return true
+	}
+
+	problemDetails := &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "SERVING_NETWORK_NOT_AUTHORIZED",
+		Detail: fmt.Sprintf("serving network [%s-%s] is not allowed", servingNetworkId.Mcc, servingNetworkId.Mnc),
+	}
+
+	logger.UeauLog.Warnln(problemDetails.Detail)
+	c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+	c.JSON(int(problemDetails.Status), problemDetails)
+	return false
+}
+
+// plmnAllowed reports whether the PLMN is in the allowed PLMN list. An empty
+// list allows every PLMN.
+func (p *Processor) plmnAllowed(mcc, mnc string) bool {
+	allowedPlmns := p.Context().AllowedPlmnList
+	if len(allowedPlmns) == 0 {
+		return true
+	}
+	// 2-digit MNCs are compared padded with a leading 0, as in the serving network name
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	for _, plmn := range allowedPlmns {
+		allowedMnc := plmn.Mnc
+		if len(allowedMnc) == 2 {
+			allowedMnc = "0" + allowedMnc
+		}
+		if plmn.Mcc == mcc && allowedMnc == mnc {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeAusf checks that the AUSF identifies itself with the NF instance id
+// its access token was granted to. Without OAuth2 there is nothing to check
+// against. On failure the error response has been written.
+func (p *Processor) authorizeAusf(c *gin.Context, ausfInstanceId string) bool {
+	subject := c.GetString(util.OAuth2SubjectCtxKey)
+	if subject == "" || strings.EqualFold(subject, ausfInstanceId) {
+		return true
+	}
+
+	problemDetails := &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  authenticationRejected,
+		Detail: fmt.Sprintf("ausfInstanceId [%s] does not match the access token", ausfInstanceId),
+	}
+
+	logger.UeauLog.Warnf("AusfInstanceId [%s] does not match the access token subject [%s]",
+		ausfInstanceId, subject)
+	c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+	c.JSON(int(problemDetails.Status), problemDetails)
+	return false
+}
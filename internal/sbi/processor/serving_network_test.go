@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/util"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+func TestAuthorizeServingNetwork(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	udmContext := &udm_context.UDMContext{}
+	mockApp.EXPECT().Context().Return(udmContext).AnyTimes()
+
+	testCases := []struct {
+		name               string
+		allowedPlmnList    []models.PlmnId
+		servingNetworkName string
+		expectStatus       int
+	}{
+		{
+			name:               "Any PLMN without allow-list",
+			servingNetworkName: "5G:mnc001.mcc001.3gppnetwork.org",
+			expectStatus:       200,
+		},
+		{
+			name:               "Malformed name",
+			servingNetworkName: "internet",
+			expectStatus:       400,
+		},
+		{
+			name:               "2-digit MNC in name",
+			servingNetworkName: "5G:mnc93.mcc208.3gppnetwork.org",
+			expectStatus:       400,
+		},
+		{
+			name:               "Allowed PLMN with 2-digit MNC",
+			allowedPlmnList:    []models.PlmnId{{Mcc: "001", Mnc: "01"}, {Mcc: "208", Mnc: "93"}},
+			servingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+			expectStatus:       200,
+		},
+		{
+			name:               "Allowed PLMN of an SNPN",
+			allowedPlmnList:    []models.PlmnId{{Mcc: "208", Mnc: "093"}},
+			servingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org:000007ed9d5",
+			expectStatus:       200,
+		},
+		{
+			name:               "Unknown visited network",
+			allowedPlmnList:    []models.PlmnId{{Mcc: "208", Mnc: "93"}},
+			servingNetworkName: "5G:mnc001.mcc001.3gppnetwork.org",
+			expectStatus:       403,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			udmContext.AllowedPlmnList = tc.allowedPlmnList
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			ok := testProcessor.authorizeServingNetwork(c, tc.servingNetworkName)
+			require.Equal(t, tc.expectStatus == 200, ok)
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+		})
+	}
+}
+
+func TestAuthorizeServingPlmn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	udmContext := &udm_context.UDMContext{}
+	mockApp.EXPECT().Context().Return(udmContext).AnyTimes()
+
+	testCases := []struct {
+		name             string
+		allowedPlmnList  []models.PlmnId
+		servingNetworkId *models.PlmnId
+		expectStatus     int
+	}{
+		{
+			name:             "Any PLMN without allow-list",
+			servingNetworkId: &models.PlmnId{Mcc: "001", Mnc: "01"},
+			expectStatus:     200,
+		},
+		{
+			name:            "No serving network",
+			allowedPlmnList: []models.PlmnId{{Mcc: "208", Mnc: "93"}},
+			expectStatus:    200,
+		},
+		{
+			name:             "Allowed PLMN with 3-digit MNC in the list",
+			allowedPlmnList:  []models.PlmnId{{Mcc: "208", Mnc: "093"}},
+			servingNetworkId: &models.PlmnId{Mcc: "208", Mnc: "93"},
+			expectStatus:     200,
+		},
+		{
+			name:             "Unknown visited network",
+			allowedPlmnList:  []models.PlmnId{{Mcc: "208", Mnc: "93"}},
+			servingNetworkId: &models.PlmnId{Mcc: "001", Mnc: "01"},
+			expectStatus:     403,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			udmContext.AllowedPlmnList = tc.allowedPlmnList
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			ok := testProcessor.authorizeServingPlmn(c, tc.servingNetworkId)
+			require.Equal(t, tc.expectStatus == 200, ok)
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+		})
+	}
+}
+
+func TestAuthorizeAusf(t *testing.T) {
+	testProcessor, err := NewProcessor(nil)
+	require.NoError(t, err)
+
+	const ausfInstanceId = "5a3f6c1e-8d2b-4c7a-9e0f-1b2c3d4e5f60"
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	require.True(t, testProcessor.authorizeAusf(c, ausfInstanceId))
+
+	c.Set(util.OAuth2SubjectCtxKey, ausfInstanceId)
+	require.True(t, testProcessor.authorizeAusf(c, ausfInstanceId))
+
+	c.Set(util.OAuth2SubjectCtxKey, "0b6e6a3c-3f4c-4d52-8b1a-7f0e2c9d1a11")
+	require.False(t, testProcessor.authorizeAusf(c, ausfInstanceId))
+	require.Equal(t, 403, httpRecorder.Code)
+}
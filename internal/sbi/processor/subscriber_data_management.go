@@ -269,9 +269,33 @@ func (p *Processor) GetSupiProcedure(c *gin.Context,
 	// if containDataSetName(dataSetNames, string(models.DataSetName_UEC_SMSF)) {
 	// }
 
-	// TODO: SMS Subscription Data
-	// if containDataSetName(dataSetNames, string(models.DataSetName_SMS_SUB)) {
-	// }
+	if p.containDataSetName(dataSetNames, string(models.DataSetName_SMS_SUB)) {
+		var querySmsDataRequest Nudr_DataRepository.QuerySmsDataRequest
+		querySmsDataRequest.SupportedFeatures = &supportedFeatures
+		querySmsDataRequest.UeId = &supi
+		querySmsDataRequest.ServingPlmnId = &plmnID
+
+		smsDataRsp, err := clientAPI.SMSSubscriptionDataDocumentApi.QuerySmsData(ctx, &querySmsDataRequest)
+		if err != nil {
+			apiError, ok := err.(openapi.GenericOpenAPIError)
+			if ok {
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+				c.JSON(apiError.ErrorStatus, apiError.RawBody)
+				return
+			}
+			problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+
+		udmUe, ok := p.Context().UdmUeFindBySupi(supi)
+		if !ok {
+			udmUe = p.Context().NewUdmUe(supi)
+		}
+		udmUe.SetSmsSubsData(&smsDataRsp.SmsSubscriptionData)
+		subscriptionDataSets.SmsSubsData = &smsDataRsp.SmsSubscriptionData
+	}
 
 	if p.containDataSetName(dataSetNames, string(models.DataSetName_SM)) {
 		querySmDataRequest.UeId = &supi
@@ -331,9 +355,34 @@ func (p *Processor) GetSupiProcedure(c *gin.Context,
 		subscriptionDataSets.TraceData = &traceDataRsp.TraceData
 	}
 
-	// TODO: SMS Management Subscription Data
-	// if containDataSetName(dataSetNames, string(models.DataSetName_SMS_MNG)) {
-	// }
+	if p.containDataSetName(dataSetNames, string(models.DataSetName_SMS_MNG)) {
+		var querySmsMngDataRequest Nudr_DataRepository.QuerySmsMngDataRequest
+		querySmsMngDataRequest.SupportedFeatures = &supportedFeatures
+		querySmsMngDataRequest.UeId = &supi
+		querySmsMngDataRequest.ServingPlmnId = &plmnID
+
+		smsMngDataRsp, err := clientAPI.SMSManagementSubscriptionDataDocumentApi.QuerySmsMngData(ctx,
+			&querySmsMngDataRequest)
+		if err != nil {
+			apiError, ok := err.(openapi.GenericOpenAPIError)
+			if ok {
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+				c.JSON(apiError.ErrorStatus, apiError.RawBody)
+				return
+			}
+			problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+
+		udmUe, ok := p.Context().UdmUeFindBySupi(supi)
+		if !ok {
+			udmUe = p.Context().NewUdmUe(supi)
+		}
+		udmUe.SetSmsMngSubsData(&smsMngDataRsp.SmsManagementSubscriptionData)
+		subscriptionDataSets.SmsMngData = &smsMngDataRsp.SmsManagementSubscriptionData
+	}
 
 	c.JSON(http.StatusOK, subscriptionDataSets)
 }
@@ -567,6 +616,92 @@ func (p *Processor) GetSmfSelectDataProcedure(c *gin.Context, supi string, plmnI
 	c.JSON(http.StatusOK, udmUe.SmfSelSubsData)
 }
 
+func (p *Processor) GetSmsDataProcedure(c *gin.Context, supi string, plmnID string, supportedFeatures string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	var querySmsDataRequest Nudr_DataRepository.QuerySmsDataRequest
+	querySmsDataRequest.SupportedFeatures = &supportedFeatures
+	querySmsDataRequest.UeId = &supi
+	querySmsDataRequest.ServingPlmnId = &plmnID
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	smsSubscriptionDataResp, err := clientAPI.SMSSubscriptionDataDocumentApi.
+		QuerySmsData(ctx, &querySmsDataRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.JSON(apiError.ErrorStatus, apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	udmUe, ok := p.Context().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = p.Context().NewUdmUe(supi)
+	}
+	udmUe.SetSmsSubsData(&smsSubscriptionDataResp.SmsSubscriptionData)
+	c.JSON(http.StatusOK, smsSubscriptionDataResp.SmsSubscriptionData)
+}
+
+func (p *Processor) GetSmsMngDataProcedure(c *gin.Context, supi string, plmnID string, supportedFeatures string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.ServiceName_NUDR_DR, models.NrfNfManagementNfType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	var querySmsMngDataRequest Nudr_DataRepository.QuerySmsMngDataRequest
+	querySmsMngDataRequest.SupportedFeatures = &supportedFeatures
+	querySmsMngDataRequest.UeId = &supi
+	querySmsMngDataRequest.ServingPlmnId = &plmnID
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	smsManagementSubscriptionDataResp, err := clientAPI.SMSManagementSubscriptionDataDocumentApi.
+		QuerySmsMngData(ctx, &querySmsMngDataRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.JSON(apiError.ErrorStatus, apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	udmUe, ok := p.Context().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = p.Context().NewUdmUe(supi)
+	}
+	udmUe.SetSmsMngSubsData(&smsManagementSubscriptionDataResp.SmsManagementSubscriptionData)
+	c.JSON(http.StatusOK, smsManagementSubscriptionDataResp.SmsManagementSubscriptionData)
+}
+
 func (p *Processor) SubscribeToSharedDataProcedure(c *gin.Context, sdmSubscription *models.SdmSubscription) {
 	if sdmSubscription.NfInstanceId == "" {
 		logger.SdmLog.Warnf("Missing mandatory parameter: nfInstanceId")
@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+)
+
+func TestGetSmsDataProcedures(t *testing.T) {
+	defer gock.Off() // Flush pending mocks after test execution
+
+	openapi.InterceptH2CClient()
+	defer openapi.RestoreH2CClient()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := new(udm_context.UdmUeContext)
+	ue.Init()
+	ue.Supi = "imsi-208930000000014"
+	ue.UdrUri = "http://127.0.0.4:8000"
+	udm_context.GetSelf().UdmUePool.Store(ue.Supi, ue)
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udm_context.GetSelf()).AnyTimes()
+
+	smsData := models.SmsSubscriptionData{SmsSubscribed: true}
+	smsMngData := models.SmsManagementSubscriptionData{MtSmsSubscribed: true, MoSmsSubscribed: true}
+	const provisionedData = "/subscription-data/imsi-208930000000014/20893/provisioned-data"
+
+	t.Run("SMS subscription data", func(t *testing.T) {
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get(provisionedData+"/sms-data").
+			Reply(200).
+			AddHeader("Content-Type", "application/json").
+			JSON(smsData)
+
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GetSmsDataProcedure(c, ue.Supi, "20893", "")
+
+		require.Equal(t, 200, httpRecorder.Code)
+		var rsp models.SmsSubscriptionData
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &rsp))
+		require.Equal(t, smsData, rsp)
+		require.Equal(t, &smsData, ue.SmsSubsData)
+	})
+
+	t.Run("SMS management subscription data", func(t *testing.T) {
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get(provisionedData+"/sms-mng-data").
+			Reply(200).
+			AddHeader("Content-Type", "application/json").
+			JSON(smsMngData)
+
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GetSmsMngDataProcedure(c, ue.Supi, "20893", "")
+
+		require.Equal(t, 200, httpRecorder.Code)
+		var rsp models.SmsManagementSubscriptionData
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &rsp))
+		require.Equal(t, smsMngData, rsp)
+		require.Equal(t, &smsMngData, ue.SmsMngSubsData)
+	})
+
+	t.Run("Unknown subscriber", func(t *testing.T) {
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get(provisionedData+"/sms-data").
+			Reply(404).
+			AddHeader("Content-Type", "application/json").
+			JSON(models.ProblemDetails{Status: 404, Cause: "USER_NOT_FOUND"})
+
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GetSmsDataProcedure(c, ue.Supi, "20893", "")
+
+		require.Equal(t, 404, httpRecorder.Code)
+	})
+
+	t.Run("Data sets", func(t *testing.T) {
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get(provisionedData+"/sms-data").
+			Reply(200).
+			AddHeader("Content-Type", "application/json").
+			JSON(smsData)
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get(provisionedData+"/sms-mng-data").
+			Reply(200).
+			AddHeader("Content-Type", "application/json").
+			JSON(smsMngData)
+
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GetSupiProcedure(c, ue.Supi, "20893",
+			[]string{string(models.DataSetName_SMS_SUB), string(models.DataSetName_SMS_MNG)}, "")
+
+		require.Equal(t, 200, httpRecorder.Code)
+		var rsp models.UdmSdmSubscriptionDataSets
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &rsp))
+		require.Equal(t, &smsData, rsp.SmsSubsData)
+		require.Equal(t, &smsMngData, rsp.SmsMngData)
+		require.Nil(t, rsp.AmData)
+		require.True(t, gock.IsDone())
+	})
+}
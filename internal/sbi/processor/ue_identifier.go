@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/suci"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+const (
+	unsupportedProtectionScheme string = "UNSUPPORTED_PROTECTION_SCHEME"
+	unknownHnPublicKey          string = "UNKNOWN_HN_PUBLIC_KEY"
+	deconcealFailure            string = "DECONCEAL_FAILURE"
+	routingIndicatorNotServed   string = "ROUTING_INDICATOR_NOT_SERVED"
+)
+
+// DeconcealProcedure returns the SUPI concealed in a SUCI, TS 29.503
+// clause 5.9.2.2.
+func (p *Processor) DeconcealProcedure(c *gin.Context, deconcealReqData models.DeconcealReqData) {
+	if _, ok := suci.RoutingIndicator(deconcealReqData.Suci); !ok {
+		problemDetails := &models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: "invalid suci",
+		}
+
+		logger.UeidLog.Errorf("Invalid suci [%s]", deconcealReqData.Suci)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	supi, err := p.Context().SuciToSupi(deconcealReqData.Suci)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Detail: err.Error(),
+		}
+		switch {
+		case errors.Is(err, suci.ErrorUnsupportedScheme):
+			problemDetails.Status = http.StatusNotImplemented
+			problemDetails.Cause = unsupportedProtectionScheme
+		case errors.Is(err, suci.ErrorUnknownPublicKey):
+			problemDetails.Cause = unknownHnPublicKey
+		case errors.Is(err, suci.ErrorMacFailure):
+			problemDetails.Cause = deconcealFailure
+		case errors.Is(err, udm_context.ErrRoutingIndicatorNotServed):
+			problemDetails.Cause = routingIndicatorNotServed
+		default:
+			problemDetails.Status = http.StatusBadRequest
+			problemDetails.Cause = "MANDATORY_IE_INCORRECT"
+		}
+
+		logger.UeidLog.Errorf("Deconceal [%s] error: %+v", deconcealReqData.Suci, err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.DeconcealRspData{Supi: supi})
+}
@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+func TestDeconcealProcedure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockApp := mockapp.NewMockApp(ctrl)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	udmContext := &udm_context.UDMContext{
+		SuciProfiles: []suci.SuciProfile{
+			{
+				ProtectionScheme: "1", // Protect Scheme: Profile A
+				PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+				PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+			},
+		},
+	}
+	mockApp.EXPECT().Context().Return(udmContext).AnyTimes()
+
+	const profileAOutput = "b2e92f836055a255837debf850b528997ce0201cb82a" +
+		"dfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+
+	testCases := []struct {
+		name              string
+		suci              string
+		routingIndicators []string
+		expectStatus      int
+		expectCause       string
+		expectSupi        string
+	}{
+		{
+			name:         "Null scheme",
+			suci:         "suci-0-208-93-0-0-0-00007487",
+			expectStatus: 200,
+			expectSupi:   "imsi-2089300007487",
+		},
+		{
+			name:         "Profile A",
+			suci:         "suci-0-208-93-0-1-1-" + profileAOutput,
+			expectStatus: 200,
+			expectSupi:   "imsi-20893001002086",
+		},
+		{
+			name:         "SUPI",
+			suci:         "imsi-2089300007487",
+			expectStatus: 400,
+			expectCause:  "MANDATORY_IE_INCORRECT",
+		},
+		{
+			name:         "Unknown key id",
+			suci:         "suci-0-208-93-0-1-5-" + profileAOutput,
+			expectStatus: 403,
+			expectCause:  unknownHnPublicKey,
+		},
+		{
+			name:         "MAC failure",
+			suci:         "suci-0-208-93-0-1-1-" + profileAOutput[:len(profileAOutput)-1] + "8",
+			expectStatus: 403,
+			expectCause:  deconcealFailure,
+		},
+		{
+			name:         "Unsupported scheme",
+			suci:         "suci-0-208-93-0-9-1-" + profileAOutput,
+			expectStatus: 501,
+			expectCause:  unsupportedProtectionScheme,
+		},
+		{
+			name:              "Routing indicator not served",
+			suci:              "suci-0-208-93-0-0-0-00007487",
+			routingIndicators: []string{"12"},
+			expectStatus:      403,
+			expectCause:       routingIndicatorNotServed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			udmContext.RoutingIndicators = tc.routingIndicators
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			testProcessor.DeconcealProcedure(c, models.DeconcealReqData{Suci: tc.suci})
+			require.Equal(t, tc.expectStatus, w.Code)
+
+			if tc.expectStatus != 200 {
+				var problemDetails models.ProblemDetails
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problemDetails))
+				require.Equal(t, tc.expectCause, problemDetails.Cause)
+				return
+			}
+			var rsp models.DeconcealRspData
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+			require.Equal(t, tc.expectSupi, rsp.Supi)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	ueau_metrics "github.com/free5gc/udm/internal/metrics/ueau"
+	"github.com/free5gc/udm/pkg/factory"
+)
+
+// vectorPool keeps the vectors of a batch that were not handed out yet, so
+// that a batch costs a single SQN update in UDR. Vectors are kept per
+// subscriber and requester (the IND key) and handed out in SQN order.
+type vectorPool struct {
+	mu        sync.Mutex
+	size      int
+	lifetime  time.Duration
+	entries   map[string]map[string]*pooledVectors // supi, pool key
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type pooledVectors struct {
+	vectors []authVector
+	sqnHe   string // SQN_HE stored in UDR for the batch
+	amf     string
+	expiry  time.Time
+}
+
+// newVectorPool returns nil, i.e. no pool, when cfg is nil
+func newVectorPool(cfg *factory.AuthVectorPool) *vectorPool {
+	if cfg == nil {
+		return nil
+	}
+	lifetime, err := time.ParseDuration(cfg.GetLifetime())
+	if err != nil {
+		logger.UeauLog.Errorf("Vector pool disabled, invalid lifetime: %+v", err)
+		return nil
+	}
+	return &vectorPool{
+		size:     cfg.GetSize(),
+		lifetime: lifetime,
+		entries:  make(map[string]map[string]*pooledVectors),
+		now:      time.Now,
+	}
+}
+
+// vectorPoolKey binds pooled vectors to the requester and to the algorithm
+// they were generated with.
+func vectorPoolKey(authSubs *models.AuthenticationSubscription, indKey string) string {
+	return indKey + "/" + strings.ToLower(authSubs.AlgorithmId)
+}
+
+// take returns the next pooled vector of supi for key. The vectors of a batch
+// are only handed out while authSubs, as just read from UDR, still holds the
+// SQN_HE and AMF the batch was stored with; otherwise another UDM has updated
+// the sequence number, or the subscription changed, and the batch is dropped.
+func (vp *vectorPool) take(supi, key string, authSubs *models.AuthenticationSubscription) (authVector, bool) {
+	vp.mu.Lock()
+	defer vp.mu.Unlock()
+	entry, ok := vp.entries[supi][key]
+	if !ok {
+		ueau_metrics.IncrVectorPoolCounter(ueau_metrics.PoolMiss)
+		return authVector{}, false
+	}
+	if vp.now().After(entry.expiry) {
+		vp.remove(supi, key)
+		ueau_metrics.IncrVectorPoolCounter(ueau_metrics.PoolExpired)
+		return authVector{}, false
+	}
+	if sqnHe, amf := pooledSubscription(authSubs); sqnHe != entry.sqnHe || amf != entry.amf {
+		logger.UeauLog.Infof("Pooled vectors of supi=[%s] dropped, subscription changed in UDR", supi)
+		vp.flushLocked(supi)
+		ueau_metrics.IncrVectorPoolCounter(ueau_metrics.PoolStale)
+		return authVector{}, false
+	}
+	vector := entry.vectors[0]
+	entry.vectors = entry.vectors[1:]
+	ueau_metrics.AddVectorPoolVectors(-1)
+	if len(entry.vectors) == 0 {
+		vp.remove(supi, key)
+	}
+	ueau_metrics.IncrVectorPoolCounter(ueau_metrics.PoolHit)
+	return vector, true
+}
+
+// put replaces the pooled vectors of supi for key. sqnHe is the SQN_HE stored
+// in UDR for the batch and amf the AMF the vectors were generated with.
+func (vp *vectorPool) put(supi, key string, vectors []authVector, sqnHe, amf string) {
+	vp.mu.Lock()
+	defer vp.mu.Unlock()
+	now := vp.now()
+	if now.After(vp.nextSweep) {
+		vp.sweep(now)
+		vp.nextSweep = now.Add(vp.lifetime)
+	}
+	vp.remove(supi, key)
+	if len(vectors) == 0 {
+		return
+	}
+	if vp.entries[supi] == nil {
+		vp.entries[supi] = make(map[string]*pooledVectors)
+	}
+	vp.entries[supi][key] = &pooledVectors{
+		vectors: vectors,
+		sqnHe:   sqnHe,
+		amf:     amf,
+		expiry:  now.Add(vp.lifetime),
+	}
+	ueau_metrics.AddVectorPoolVectors(len(vectors))
+}
+
+// flush drops the pooled vectors of supi, e.g. after a re-synchronisation
+func (vp *vectorPool) flush(supi string) {
+	vp.mu.Lock()
+	defer vp.mu.Unlock()
+	vp.flushLocked(supi)
+}
+
+func (vp *vectorPool) flushLocked(supi string) {
+	for key := range vp.entries[supi] {
+		vp.remove(supi, key)
+	}
+}
+
+// pooledSubscription returns the SQN_HE and AMF of authSubs pooled vectors
+// are checked against
+func pooledSubscription(authSubs *models.AuthenticationSubscription) (sqnHe, amf string) {
+	if authSubs.SequenceNumber != nil {
+		sqnHe = authSubs.SequenceNumber.Sqn
+	}
+	return sqnHe, authSubs.AuthenticationManagementField
+}
+
+func (vp *vectorPool) sweep(now time.Time) {
+	for supi, entries := range vp.entries {
+		for key, entry := range entries {
+			if now.After(entry.expiry) {
+				vp.remove(supi, key)
+			}
+		}
+	}
+}
+
+func (vp *vectorPool) remove(supi, key string) {
+	entry, ok := vp.entries[supi][key]
+	if !ok {
+		return
+	}
+	ueau_metrics.AddVectorPoolVectors(-len(entry.vectors))
+	delete(vp.entries[supi], key)
+	if len(vp.entries[supi]) == 0 {
+		delete(vp.entries, supi)
+	}
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/pkg/factory"
+)
+
+func TestVectorPool(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	vp := newVectorPool(&factory.AuthVectorPool{Size: 3, Lifetime: "1m"})
+	require.NotNil(t, vp)
+	vp.now = func() time.Time { return now }
+
+	vectors := []authVector{{Rand: []byte{1}}, {Rand: []byte{2}}}
+	authSubs := &models.AuthenticationSubscription{
+		SequenceNumber:                &models.SequenceNumber{Sqn: "000000000026"},
+		AuthenticationManagementField: "8000",
+	}
+
+	vp.put("imsi-1", "a", vectors, "000000000026", "8000")
+	vector, ok := vp.take("imsi-1", "a", authSubs)
+	require.True(t, ok)
+	require.Equal(t, vectors[0], vector)
+	_, ok = vp.take("imsi-1", "b", authSubs)
+	require.False(t, ok)
+	vector, ok = vp.take("imsi-1", "a", authSubs)
+	require.True(t, ok)
+	require.Equal(t, vectors[1], vector)
+	_, ok = vp.take("imsi-1", "a", authSubs)
+	require.False(t, ok)
+	require.Empty(t, vp.entries)
+
+	vp.put("imsi-1", "a", vectors, "000000000026", "8000")
+	now = now.Add(2 * time.Minute)
+	_, ok = vp.take("imsi-1", "a", authSubs)
+	require.False(t, ok)
+	require.Empty(t, vp.entries)
+
+	vp.put("imsi-1", "a", vectors, "000000000026", "8000")
+	vp.put("imsi-1", "b", vectors, "000000000026", "8000")
+	vp.put("imsi-2", "a", vectors, "000000000026", "8000")
+	vp.flush("imsi-1")
+	_, ok = vp.take("imsi-1", "b", authSubs)
+	require.False(t, ok)
+	_, ok = vp.take("imsi-2", "a", authSubs)
+	require.True(t, ok)
+
+	// vectors are dropped once SQN_HE in UDR no longer matches the batch
+	vp.put("imsi-1", "a", vectors, "000000000026", "8000")
+	_, ok = vp.take("imsi-1", "a", &models.AuthenticationSubscription{
+		SequenceNumber:                &models.SequenceNumber{Sqn: "000000000040"},
+		AuthenticationManagementField: "8000",
+	})
+	require.False(t, ok)
+	require.NotContains(t, vp.entries, "imsi-1")
+
+	// put sweeps the entries of other subscribers once expired
+	now = now.Add(2 * time.Minute)
+	vp.put("imsi-3", "a", vectors, "000000000026", "8000")
+	require.NotContains(t, vp.entries, "imsi-2")
+
+	require.Nil(t, newVectorPool(nil))
+}
@@ -255,5 +255,13 @@ func newRouter(s *Server) *gin.Engine {
 	})
 	AddService(udmUEIDGroup, udmUEIDRoutes)
 
+	// Admin
+	if admin := s.Config().GetAdmin(); admin != nil && admin.Enable {
+		udmAdminRoutes := s.getAdminRoutes()
+		udmAdminGroup := router.Group(factory.UdmAdminResUriPrefix)
+		udmAdminGroup.Use(adminAuthorizationCheck(admin.Token))
+		AddService(udmAdminGroup, udmAdminRoutes)
+	}
+
 	return router
 }
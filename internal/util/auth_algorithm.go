@@ -0,0 +1,162 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/util/milenage"
+)
+
+// Algorithm ids as stored in AuthenticationSubscription.AlgorithmId.
+const (
+	AuthAlgorithmMilenage = "milenage"
+	AuthAlgorithmTuak     = "tuak"
+)
+
+// AuthAlgorithmParams gives, in octets, the key lengths an AuthAlgorithm accepts
+// and the output lengths it produces.
+type AuthAlgorithmParams struct {
+	KeyLengths []int
+	OpcLength  int
+	MacLength  int
+	ResLength  int
+	CkLength   int
+	IkLength   int
+}
+
+// AuthAlgorithm is a set of authentication and key generation functions
+// (f1, f1*, f2-f5, f5*) as defined in 3GPP TS 33.102 clause 6.3.
+// Output buffers are sized by the caller according to Params.
+type AuthAlgorithm interface {
+	Params() AuthAlgorithmParams
+	// DeriveOpc computes the operator variant key (OPc, TOPc) from K and OP (TOP).
+	DeriveOpc(k, op []byte) ([]byte, error)
+	F1(opc, k, rand, sqn, amf, macA []byte) error
+	F1Star(opc, k, rand, sqn, amf, macS []byte) error
+	F2345(opc, k, rand []byte, res, ck, ik, ak []byte) error
+	F5Star(opc, k, rand, akstar []byte) error
+}
+
+var (
+	authAlgorithmsMu sync.RWMutex
+	authAlgorithms   = map[string]AuthAlgorithm{
+		AuthAlgorithmMilenage: milenageAlgorithm{},
+		AuthAlgorithmTuak:     NewTuakAlgorithm(nil),
+	}
+)
+
+// RegisterAuthAlgorithm registers alg under the given algorithm id, replacing
+// any previous registration. Ids are case-insensitive.
+func RegisterAuthAlgorithm(id string, alg AuthAlgorithm) {
+	authAlgorithmsMu.Lock()
+	defer authAlgorithmsMu.Unlock()
+	authAlgorithms[strings.ToLower(id)] = alg
+}
+
+// legacyAuthAlgorithmIds are the algorithm ids of subscriptions provisioned
+// before the algorithm could be selected, all of them use MILENAGE.
+var legacyAuthAlgorithmIds = map[string]bool{
+	"":         true,
+	"128-eea0": true,
+}
+
+// GetAuthAlgorithm returns the algorithm registered under id. An empty or
+// legacy id selects MILENAGE, any other unregistered id is not supported.
+func GetAuthAlgorithm(id string) (AuthAlgorithm, bool) {
+	authAlgorithmsMu.RLock()
+	defer authAlgorithmsMu.RUnlock()
+	id = strings.ToLower(id)
+	if legacyAuthAlgorithmIds[id] {
+		id = AuthAlgorithmMilenage
+	}
+	alg, ok := authAlgorithms[id]
+	return alg, ok
+}
+
+type milenageAlgorithm struct{}
+
+func (milenageAlgorithm) Params() AuthAlgorithmParams {
+	return AuthAlgorithmParams{
+		KeyLengths: []int{16},
+		OpcLength:  16,
+		MacLength:  8,
+		ResLength:  8,
+		CkLength:   16,
+		IkLength:   16,
+	}
+}
+
+func (milenageAlgorithm) DeriveOpc(k, op []byte) ([]byte, error) {
+	return milenage.GenerateOPc(k, op)
+}
+
+func (milenageAlgorithm) F1(opc, k, rand, sqn, amf, macA []byte) error {
+	return MilenageF1(opc, k, rand, sqn, amf, macA, nil)
+}
+
+func (milenageAlgorithm) F1Star(opc, k, rand, sqn, amf, macS []byte) error {
+	return MilenageF1(opc, k, rand, sqn, amf, nil, macS)
+}
+
+func (milenageAlgorithm) F2345(opc, k, rand []byte, res, ck, ik, ak []byte) error {
+	return MilenageF2345(opc, k, rand, res, ck, ik, ak, nil)
+}
+
+func (milenageAlgorithm) F5Star(opc, k, rand, akstar []byte) error {
+	out5, err := milenageOut5(opc, k, rand)
+	if err != nil {
+		return err
+	}
+	copy(akstar, out5[:6])
+	return nil
+}
+
+type tuakAlgorithm struct {
+	params           AuthAlgorithmParams
+	keccakIterations int
+}
+
+// NewTuakAlgorithm returns the TUAK algorithm set with the output lengths and
+// Keccak iterations of cfg; a nil cfg uses the defaults.
+func NewTuakAlgorithm(cfg *factory.Tuak) AuthAlgorithm {
+	return &tuakAlgorithm{
+		params: AuthAlgorithmParams{
+			KeyLengths: []int{16, 32},
+			OpcLength:  TuakTopLen,
+			MacLength:  cfg.GetMacLength() / 8,
+			ResLength:  cfg.GetResLength() / 8,
+			CkLength:   cfg.GetCkLength() / 8,
+			IkLength:   cfg.GetIkLength() / 8,
+		},
+		keccakIterations: cfg.GetKeccakIterations(),
+	}
+}
+
+func (t *tuakAlgorithm) Params() AuthAlgorithmParams {
+	return t.params
+}
+
+func (t *tuakAlgorithm) DeriveOpc(k, op []byte) ([]byte, error) {
+	return TuakTopc(op, k, t.keccakIterations)
+}
+
+func (t *tuakAlgorithm) F1(opc, k, rand, sqn, amf, macA []byte) error {
+	return TuakF1(opc, k, rand, sqn, amf, macA, nil, t.keccakIterations)
+}
+
+func (t *tuakAlgorithm) F1Star(opc, k, rand, sqn, amf, macS []byte) error {
+	return TuakF1(opc, k, rand, sqn, amf, nil, macS, t.keccakIterations)
+}
+
+func (t *tuakAlgorithm) F2345(opc, k, rand []byte, res, ck, ik, ak []byte) error {
+	return TuakF2345(opc, k, rand, res, ck, ik, ak, nil, t.keccakIterations)
+}
+
+func (t *tuakAlgorithm) F5Star(opc, k, rand, akstar []byte) error {
+	if len(akstar) < 6 {
+		return fmt.Errorf("TUAK: AK* buffer too short")
+	}
+	return TuakF2345(opc, k, rand, nil, nil, nil, nil, akstar, t.keccakIterations)
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/util/milenage"
+)
+
+// 3GPP TS 35.208 test set 1
+func TestMilenageAuthAlgorithm(t *testing.T) {
+	k := decodeHex(t, "465b5ce8b199b49faa5f0a2ee238a6bc")
+	rand := decodeHex(t, "23553cbe9637a89d218ae64dae47bf35")
+	sqn := decodeHex(t, "ff9bb4d0b607")
+	amf := decodeHex(t, "b9b9")
+	opc := decodeHex(t, "cd63cb71954a9f4e48a5994e37a02baf")
+
+	alg, ok := GetAuthAlgorithm("")
+	require.True(t, ok)
+	params := alg.Params()
+
+	macA, macS := make([]byte, params.MacLength), make([]byte, params.MacLength)
+	require.NoError(t, alg.F1(opc, k, rand, sqn, amf, macA))
+	require.NoError(t, alg.F1Star(opc, k, rand, sqn, amf, macS))
+	require.Equal(t, "4a9ffac354dfafb3", hex.EncodeToString(macA))
+	require.Equal(t, "01cfaf9ec4e871e9", hex.EncodeToString(macS))
+
+	res, ck, ik := make([]byte, params.ResLength), make([]byte, params.CkLength), make([]byte, params.IkLength)
+	ak, akstar := make([]byte, 6), make([]byte, 6)
+	require.NoError(t, alg.F2345(opc, k, rand, res, ck, ik, ak))
+	require.NoError(t, alg.F5Star(opc, k, rand, akstar))
+	require.Equal(t, "a54211d5e3ba50bf", hex.EncodeToString(res))
+	require.Equal(t, "b40ba9a3c58b2a05bbf0d987b21bf8cb", hex.EncodeToString(ck))
+	require.Equal(t, "f769bcd751044604127672711c6d3441", hex.EncodeToString(ik))
+	require.Equal(t, "aa689c648370", hex.EncodeToString(ak))
+	require.Equal(t, "451e8beca43b", hex.EncodeToString(akstar))
+
+	// AUTS built by the milenage package must be recoverable through F5* and F1*
+	auts, err := milenage.GenerateAUTS(opc, k, rand, sqn)
+	require.NoError(t, err)
+	sqnMs := make([]byte, 6)
+	for i := range sqnMs {
+		sqnMs[i] = auts[i] ^ akstar[i]
+	}
+	require.Equal(t, sqn, sqnMs)
+	require.NoError(t, alg.F1Star(opc, k, rand, sqnMs, []byte{0x00, 0x00}, macS))
+	require.Equal(t, auts[6:], macS)
+}
+
+type fakeAuthAlgorithm struct {
+	milenageAlgorithm
+}
+
+func TestAuthAlgorithmRegistry(t *testing.T) {
+	for id, expect := range map[string]AuthAlgorithm{
+		"":         milenageAlgorithm{},
+		"128-EEA0": milenageAlgorithm{},
+		"Milenage": milenageAlgorithm{},
+		"TUAK":     &tuakAlgorithm{},
+	} {
+		alg, ok := GetAuthAlgorithm(id)
+		require.True(t, ok, id)
+		require.IsType(t, expect, alg, id)
+	}
+	_, ok := GetAuthAlgorithm("fake")
+	require.False(t, ok)
+
+	RegisterAuthAlgorithm("Fake", fakeAuthAlgorithm{})
+	defer func() {
+		authAlgorithmsMu.Lock()
+		delete(authAlgorithms, "fake")
+		authAlgorithmsMu.Unlock()
+	}()
+	alg, ok := GetAuthAlgorithm("fake")
+	require.True(t, ok)
+	require.IsType(t, fakeAuthAlgorithm{}, alg)
+}
@@ -0,0 +1,213 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/keybackend"
+)
+
+// KeyEncryptionKey decrypts subscriber keys (K, OPc, OP) that are stored
+// encrypted in the UDR. aad is authenticated by AEAD algorithms and ignored by
+// key wrap algorithms.
+type KeyEncryptionKey interface {
+	Decrypt(ciphertext, aad []byte) ([]byte, error)
+}
+
+var (
+	keksMu             sync.RWMutex
+	keks               = map[string]KeyEncryptionKey{}
+	allowPlaintextKeys bool
+)
+
+// InitKeyEncryptionKeys replaces the registered KEKs with the configured ones.
+func InitKeyEncryptionKeys(cfgs []factory.KeyEncryptionKey) error {
+	newKeks := make(map[string]KeyEncryptionKey, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.KeyBackend != "" {
+			newKeks[cfg.KeyId] = &backendKek{
+				backend:   cfg.KeyBackend,
+				keyLabel:  cfg.KeyLabel,
+				algorithm: cfg.Algorithm,
+			}
+			continue
+		}
+		key, err := hex.DecodeString(cfg.Key)
+		if err != nil {
+			return fmt.Errorf("KEK [%s]: %w", cfg.KeyId, err)
+		}
+		kek, err := NewKeyEncryptionKey(cfg.Algorithm, key)
+		if err != nil {
+			return fmt.Errorf("KEK [%s]: %w", cfg.KeyId, err)
+		}
+		newKeks[cfg.KeyId] = kek
+	}
+
+	keksMu.Lock()
+	defer keksMu.Unlock()
+	keks = newKeks
+	return nil
+}
+
+// RegisterKeyEncryptionKey registers kek under keyId, replacing any previous one.
+func RegisterKeyEncryptionKey(keyId string, kek KeyEncryptionKey) {
+	keksMu.Lock()
+	defer keksMu.Unlock()
+	keks[keyId] = kek
+}
+
+func GetKeyEncryptionKey(keyId string) (KeyEncryptionKey, bool) {
+	keksMu.RLock()
+	defer keksMu.RUnlock()
+	kek, ok := keks[keyId]
+	return kek, ok
+}
+
+// KeyEncryptionEnabled reports whether any KEK is configured. Without KEKs the
+// subscriber keys in the UDR are taken as plaintext.
+func KeyEncryptionEnabled() bool {
+	keksMu.RLock()
+	defer keksMu.RUnlock()
+	return len(keks) > 0
+}
+
+// SetAllowPlaintextKeys sets whether subscriber keys without a
+// protectionParameterId are accepted as plaintext while KEKs are configured.
+func SetAllowPlaintextKeys(allow bool) {
+	keksMu.Lock()
+	defer keksMu.Unlock()
+	allowPlaintextKeys = allow
+}
+
+// PlaintextKeysAllowed reports whether subscriber keys without a
+// protectionParameterId are accepted as plaintext.
+func PlaintextKeysAllowed() bool {
+	keksMu.RLock()
+	defer keksMu.RUnlock()
+	return len(keks) == 0 || allowPlaintextKeys
+}
+
+// SubscriberKeyAad returns the additional authenticated data binding an
+// AES-256-GCM encrypted subscriber key to its subscriber and field: the SUPI
+// and the AuthenticationSubscription field name separated by '|', e.g.
+// "imsi-208930000000001|encPermanentKey". Provisioning must encrypt with the
+// same AAD so that a key copied to another subscriber or field fails to decrypt.
+func SubscriberKeyAad(supi, field string) []byte {
+	return []byte(supi + "|" + field)
+}
+
+// DecryptSubscriberKey decrypts a hex encoded subscriber key with the KEK
+// referenced by keyId and returns the plaintext key hex encoded. aad is built
+// with SubscriberKeyAad.
+func DecryptSubscriberKey(keyId, encKey string, aad []byte) (string, error) {
+	kek, ok := GetKeyEncryptionKey(keyId)
+	if !ok {
+		return "", fmt.Errorf("unknown key encryption key [%s]", keyId)
+	}
+	ciphertext, err := hex.DecodeString(encKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := kek.Decrypt(ciphertext, aad)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(plaintext), nil
+}
+
+func NewKeyEncryptionKey(algorithm string, key []byte) (KeyEncryptionKey, error) {
+	switch strings.ToUpper(algorithm) {
+	case factory.KekAlgorithmAes256Gcm:
+		if len(key) != 32 {
+			return nil, fmt.Errorf("AES-256-GCM KEK must be 256 bits")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return &aesGcmKek{aead: aead}, nil
+	case factory.KekAlgorithmAesKw:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return &aesKwKek{block: block}, nil
+	default:
+		return nil, fmt.Errorf("unsupported KEK algorithm [%s]", algorithm)
+	}
+}
+
+// aesGcmKek expects nonce (96 bits) || ciphertext || tag (128 bits).
+type aesGcmKek struct {
+	aead cipher.AEAD
+}
+
+func (k *aesGcmKek) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	nonceSize := k.aead.NonceSize()
+	if len(ciphertext) < nonceSize+k.aead.Overhead() {
+		return nil, fmt.Errorf("AES-GCM ciphertext too short")
+	}
+	return k.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], aad)
+}
+
+// aesKwKek implements the AES key unwrap of RFC 3394.
+type aesKwKek struct {
+	block cipher.Block
+}
+
+var aesKwDefaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+func (k *aesKwKek) Decrypt(ciphertext, _ []byte) ([]byte, error) {
+	if len(ciphertext) < 24 || len(ciphertext)%8 != 0 {
+		return nil, fmt.Errorf("AES-KW ciphertext must be a multiple of 64 bits and at least 192 bits")
+	}
+
+	n := len(ciphertext)/8 - 1
+	a := make([]byte, 8)
+	copy(a, ciphertext[:8])
+	r := make([]byte, n*8)
+	copy(r, ciphertext[8:])
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			k.block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[(i-1)*8:i*8], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, aesKwDefaultIV) != 1 {
+		return nil, fmt.Errorf("AES-KW integrity check failed")
+	}
+	return r, nil
+}
+
+// backendKek unwraps inside a key backend, the KEK itself is never loaded.
+type backendKek struct {
+	backend   string
+	keyLabel  string
+	algorithm string
+}
+
+func (k *backendKek) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	backend, err := keybackend.Get(k.backend)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Unwrap(k.keyLabel, k.algorithm, ciphertext, aad)
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/keybackend"
+)
+
+// RFC 3394 clause 4 test vectors
+func TestAesKwKek(t *testing.T) {
+	testCases := []struct {
+		kek        string
+		ciphertext string
+		plaintext  string
+	}{
+		{
+			kek:        "000102030405060708090a0b0c0d0e0f",
+			ciphertext: "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+			plaintext:  "00112233445566778899aabbccddeeff",
+		},
+		{
+			kek:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			ciphertext: "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7",
+			plaintext:  "00112233445566778899aabbccddeeff",
+		},
+	}
+
+	for _, tc := range testCases {
+		kek, err := NewKeyEncryptionKey(factory.KekAlgorithmAesKw, decodeHex(t, tc.kek))
+		require.NoError(t, err)
+		plaintext, err := kek.Decrypt(decodeHex(t, tc.ciphertext), nil)
+		require.NoError(t, err)
+		require.Equal(t, tc.plaintext, hex.EncodeToString(plaintext))
+
+		tampered := decodeHex(t, tc.ciphertext)
+		tampered[0] ^= 0x01
+		_, err = kek.Decrypt(tampered, nil)
+		require.Error(t, err)
+	}
+}
+
+func TestDecryptSubscriberKey(t *testing.T) {
+	key := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	require.NoError(t, InitKeyEncryptionKeys([]factory.KeyEncryptionKey{
+		{KeyId: "kek-old", Algorithm: factory.KekAlgorithmAesKw, Key: key},
+		{KeyId: "kek-new", Algorithm: factory.KekAlgorithmAes256Gcm, Key: key},
+	}))
+	defer func() {
+		require.NoError(t, InitKeyEncryptionKeys(nil))
+	}()
+	require.True(t, KeyEncryptionEnabled())
+
+	subscriberKey := decodeHex(t, "8baf473f2f8fd09487cccbd7097c6862")
+	block, err := aes.NewCipher(decodeHex(t, key))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	aad := SubscriberKeyAad("imsi-208930000000001", "encPermanentKey")
+	require.Equal(t, "imsi-208930000000001|encPermanentKey", string(aad))
+	encKey := hex.EncodeToString(aead.Seal(nonce, nonce, subscriberKey, aad))
+
+	plaintext, err := DecryptSubscriberKey("kek-new", encKey, aad)
+	require.NoError(t, err)
+	require.Equal(t, "8baf473f2f8fd09487cccbd7097c6862", plaintext)
+
+	// the key does not decrypt for another subscriber or field
+	_, err = DecryptSubscriberKey("kek-new", encKey, SubscriberKeyAad("imsi-208930000000002", "encPermanentKey"))
+	require.Error(t, err)
+	_, err = DecryptSubscriberKey("kek-new", encKey, SubscriberKeyAad("imsi-208930000000001", "encOpcKey"))
+	require.Error(t, err)
+
+	plaintext, err = DecryptSubscriberKey("kek-old", "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7", aad)
+	require.NoError(t, err)
+	require.Equal(t, "00112233445566778899aabbccddeeff", plaintext)
+
+	_, err = DecryptSubscriberKey("kek-unknown", encKey, aad)
+	require.Error(t, err)
+}
+
+// softBackend keeps the KEKs in memory, standing in for an HSM
+type softBackend struct {
+	keys map[string][]byte
+}
+
+func (b *softBackend) ECDH(keyLabel string, curve ecdh.Curve, peerPublicKey []byte) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (b *softBackend) Unwrap(keyLabel, mechanism string, wrappedKey, aad []byte) ([]byte, error) {
+	kek, err := NewKeyEncryptionKey(mechanism, b.keys[keyLabel])
+	if err != nil {
+		return nil, err
+	}
+	return kek.Decrypt(wrappedKey, aad)
+}
+
+func (b *softBackend) Close() error {
+	return nil
+}
+
+func TestDecryptSubscriberKeyWithKeyBackend(t *testing.T) {
+	keybackend.Register("soft", &softBackend{keys: map[string][]byte{
+		"kek-label": decodeHex(t, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+	}})
+	require.NoError(t, InitKeyEncryptionKeys([]factory.KeyEncryptionKey{
+		{KeyId: "kek-hsm", Algorithm: factory.KekAlgorithmAesKw, KeyBackend: "soft", KeyLabel: "kek-label"},
+		{KeyId: "kek-missing", Algorithm: factory.KekAlgorithmAesKw, KeyBackend: "soft", KeyLabel: "none"},
+	}))
+	defer func() {
+		require.NoError(t, InitKeyEncryptionKeys(nil))
+		require.NoError(t, keybackend.CloseAll())
+	}()
+
+	plaintext, err := DecryptSubscriberKey("kek-hsm", "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7", nil)
+	require.NoError(t, err)
+	require.Equal(t, "00112233445566778899aabbccddeeff", plaintext)
+
+	_, err = DecryptSubscriberKey("kek-missing", "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7", nil)
+	require.Error(t, err)
+}
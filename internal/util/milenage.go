@@ -1,7 +1,8 @@
 package util
 
 import (
-	"encoding/hex"
+	"crypto/aes"
+	"fmt"
 
 	"github.com/free5gc/util/milenage"
 )
@@ -22,24 +23,13 @@ func MilenageF1(opc, k, rand, sqn, amf []byte, macA, macS []byte) error {
 		copy(macA, autn[len(autn)-8:])
 	}
 
-	// For MAC-S, use resync AMF (0000)
+	// MAC-S (f1*) is not exported by the milenage package
 	if macS != nil {
-		resyncAMFBytes, err := hex.DecodeString("0000")
+		out1, err := milenageOut1(opc, k, rand, sqn, amf)
 		if err != nil {
 			return err
 		}
-		ikS, ckS, xresS, autnS, err := milenage.GenerateAKAParameters(opc, k, rand, sqn, resyncAMFBytes)
-		if err != nil {
-			return err
-		}
-		// Suppress unused variable warnings
-		_ = ikS
-		_ = ckS
-		_ = xresS
-
-		if len(autnS) >= 8 {
-			copy(macS, autnS[len(autnS)-8:])
-		}
+		copy(macS, out1[8:])
 	}
 
 	return nil
@@ -77,9 +67,91 @@ func MilenageF2345(opc, k, rand []byte, res, ck, ik, ak, akstar []byte) error {
 		copy(ak, akOut)
 	}
 	if akstar != nil {
-		// For AK*, we need to use a different SQN, but due to API limitations, we use the same value for now
-		copy(akstar, akOut)
+		// AK* (f5*) is not exported by the milenage package
+		out5, err := milenageOut5(opc, k, rand)
+		if err != nil {
+			return err
+		}
+		copy(akstar, out5[:6])
 	}
 
 	return nil
 }
+
+// milenageTemp returns TEMP = E_K(RAND XOR OPc), TS 35.206 clause 4.1.
+func milenageTemp(opc, k, rand []byte) ([]byte, error) {
+	if len(opc) != milenage.OPC_LEN || len(rand) != milenage.RAND_LEN {
+		return nil, fmt.Errorf("milenage: OPc and RAND must be 128 bits")
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	temp := make([]byte, milenage.CIPHER_BLOCK_LEN)
+	for i := range temp {
+		temp[i] = rand[i] ^ opc[i]
+	}
+	block.Encrypt(temp, temp)
+	return temp, nil
+}
+
+// milenageOut1 returns OUT1 = E_K(TEMP XOR rot(IN1 XOR OPc, r1) XOR c1) XOR OPc,
+// where MAC-A is OUT1[0:8] and MAC-S is OUT1[8:16].
+func milenageOut1(opc, k, rand, sqn, amf []byte) ([]byte, error) {
+	if len(sqn) != milenage.SQN_LEN || len(amf) != milenage.AMF_LEN {
+		return nil, fmt.Errorf("milenage: SQN must be 48 bits and AMF 16 bits")
+	}
+	temp, err := milenageTemp(opc, k, rand)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+
+	in1 := make([]byte, milenage.CIPHER_BLOCK_LEN)
+	copy(in1[0:], sqn)
+	copy(in1[6:], amf)
+	copy(in1[8:], sqn)
+	copy(in1[14:], amf)
+
+	// r1 = 64 bits, c1 = 0
+	out1 := make([]byte, milenage.CIPHER_BLOCK_LEN)
+	for i := range out1 {
+		out1[(i+8)%milenage.CIPHER_BLOCK_LEN] = in1[i] ^ opc[i]
+	}
+	for i := range out1 {
+		out1[i] ^= temp[i]
+	}
+	block.Encrypt(out1, out1)
+	for i := range out1 {
+		out1[i] ^= opc[i]
+	}
+	return out1, nil
+}
+
+// milenageOut5 returns OUT5 = E_K(rot(TEMP XOR OPc, r5) XOR c5) XOR OPc,
+// where AK* is OUT5[0:6].
+func milenageOut5(opc, k, rand []byte) ([]byte, error) {
+	temp, err := milenageTemp(opc, k, rand)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+
+	// r5 = 96 bits, c5 = ..08
+	out5 := make([]byte, milenage.CIPHER_BLOCK_LEN)
+	for i := range out5 {
+		out5[(i+4)%milenage.CIPHER_BLOCK_LEN] = temp[i] ^ opc[i]
+	}
+	out5[15] ^= 8
+	block.Encrypt(out5, out5)
+	for i := range out5 {
+		out5[i] ^= opc[i]
+	}
+	return out5, nil
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/free5gc/udm/internal/logger"
 )
 
+// OAuth2SubjectCtxKey is the gin context key of the NF instance id the
+// verified access token was granted to
+const OAuth2SubjectCtxKey = "oauth2Subject"
+
 type NFContextGetter func() *udm_context.UDMContext
 
 type RouterAuthorizationCheck struct {
@@ -31,6 +35,9 @@ func (rac *RouterAuthorizationCheck) Check(c *gin.Context, udmContext udm_contex
 		c.Abort()
 		return
 	}
+	if subject := udmContext.TokenSubject(token); subject != "" {
+		c.Set(OAuth2SubjectCtxKey, subject)
+	}
 
 	logger.UtilLog.Debugf("RouterAuthorizationCheck::Check Authorized")
 }
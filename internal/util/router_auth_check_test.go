@@ -14,6 +14,7 @@ import (
 const (
 	Valid   = "valid"
 	Invalid = "invalid"
+	Subject = "subject"
 )
 
 type mockUDMContext struct{}
@@ -30,6 +31,13 @@ func (m *mockUDMContext) AuthorizationCheck(token string, serviceName models.Ser
 	return errors.New("invalid token")
 }
 
+func (m *mockUDMContext) TokenSubject(token string) string {
+	if token == Valid {
+		return Subject
+	}
+	return ""
+}
+
 func TestRouterAuthorizationCheck_Check(t *testing.T) {
 	// Mock gin.Context
 	w := httptest.NewRecorder()
@@ -46,6 +54,7 @@ func TestRouterAuthorizationCheck_Check(t *testing.T) {
 	}
 	type Want struct {
 		statusCode int
+		subject    string
 	}
 
 	tests := []struct {
@@ -60,6 +69,7 @@ func TestRouterAuthorizationCheck_Check(t *testing.T) {
 			},
 			want: Want{
 				statusCode: http.StatusOK,
+				subject:    Subject,
 			},
 		},
 		{
@@ -88,6 +98,9 @@ func TestRouterAuthorizationCheck_Check(t *testing.T) {
 			if w.Code != tt.want.statusCode {
 				t.Errorf("StatusCode should be %d, but got %d", tt.want.statusCode, w.Code)
 			}
+			if subject := c.GetString(OAuth2SubjectCtxKey); subject != tt.want.subject {
+				t.Errorf("Subject should be %q, but got %q", tt.want.subject, subject)
+			}
 		})
 	}
 }
@@ -0,0 +1,217 @@
+package util
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/pkg/factory"
+)
+
+// SQN management profiles of 3GPP TS 33.102 Annex C.3
+const (
+	SqnProfilePartlyTimeBased = 1
+	SqnProfileNonTimeBased    = 2
+	SqnProfileTimeBased       = 3
+)
+
+const (
+	sqnLength = 48
+	// SqnMask keeps the 48 bits of an SQN, SQN arithmetic is modulo 2^48
+	SqnMask = 1<<sqnLength - 1
+	// length of the time-based SEQ2 of profile 1, Annex C.3.1
+	seq2Length = 24
+)
+
+// SqnManager generates SQN = SEQ || IND as in 3GPP TS 33.102 Annex C so that
+// vectors handed out to different serving networks can be used out of order.
+type SqnManager struct {
+	profile        int
+	indLength      uint
+	delta          uint64
+	freshnessLimit uint64
+	timeUnit       time.Duration
+	indByAusf      bool
+	now            func() time.Time
+}
+
+var (
+	sqnManagerMu sync.RWMutex
+	sqnManager   *SqnManager
+)
+
+// InitSqnManagement sets the SQN management used for vector generation. A nil
+// cfg keeps the SQN as a plain 48-bit counter.
+func InitSqnManagement(cfg *factory.SqnManagement) error {
+	var m *SqnManager
+	if cfg != nil {
+		timeUnit, err := time.ParseDuration(cfg.GetTimeUnit())
+		if err != nil {
+			return err
+		}
+		m = &SqnManager{
+			profile:        cfg.Profile,
+			indLength:      uint(cfg.GetIndLength()),
+			delta:          cfg.GetDelta(),
+			freshnessLimit: cfg.FreshnessLimit,
+			timeUnit:       timeUnit,
+			indByAusf:      cfg.GetIndAllocation() == factory.SqnIndByAusf,
+			now:            time.Now,
+		}
+	}
+	sqnManagerMu.Lock()
+	defer sqnManagerMu.Unlock()
+	sqnManager = m
+	return nil
+}
+
+// GetSqnManager returns nil when no SQN management is configured.
+func GetSqnManager() *SqnManager {
+	sqnManagerMu.RLock()
+	defer sqnManagerMu.RUnlock()
+	return sqnManager
+}
+
+// IndByAusf tells whether the IND is allocated per AUSF instead of per serving
+// network.
+func (m *SqnManager) IndByAusf() bool {
+	return m.indByAusf
+}
+
+func (m *SqnManager) IndLength() int32 {
+	return int32(m.indLength)
+}
+
+func (m *SqnManager) SqnScheme() models.SqnScheme {
+	switch m.profile {
+	case SqnProfileNonTimeBased:
+		return models.SqnScheme_NON_TIME_BASED
+	case SqnProfileTimeBased:
+		return models.SqnScheme_TIME_BASED
+	default:
+		return models.SqnScheme_GENERAL
+	}
+}
+
+func (m *SqnManager) seqMask() uint64 {
+	return 1<<(sqnLength-m.indLength) - 1
+}
+
+func (m *SqnManager) seq(sqn uint64) uint64 {
+	return (sqn & SqnMask) >> m.indLength
+}
+
+func (m *SqnManager) sqn(seq uint64, ind int32) uint64 {
+	return (seq&m.seqMask())<<m.indLength | uint64(ind)&(1<<m.indLength-1)
+}
+
+// glc is the global time-based counter of Annex C.3.1 and C.3.3
+func (m *SqnManager) glc() uint64 {
+	return uint64(m.now().UnixNano() / int64(m.timeUnit))
+}
+
+func (m *SqnManager) nextSeq(seqHe uint64) uint64 {
+	next := (seqHe + 1) & m.seqMask()
+	switch m.profile {
+	case SqnProfileTimeBased:
+		if glc := m.glc() & m.seqMask(); glc > next {
+			next = glc
+		}
+	case SqnProfilePartlyTimeBased:
+		// SEQ = SEQ1 || SEQ2, SEQ2 follows the clock and SEQ1 counts
+		// whenever SEQ2 can not advance
+		seq2Mask := uint64(1<<seq2Length - 1)
+		if glc := m.glc() & seq2Mask; glc > seqHe&seq2Mask {
+			next = (seqHe&^seq2Mask | glc) & m.seqMask()
+		}
+	}
+	return next
+}
+
+// Generate returns the SQNs of n vectors with the given IND following sqnHe,
+// and the new SQN_HE to store.
+func (m *SqnManager) Generate(sqnHe uint64, ind int32, n int) ([]uint64, uint64) {
+	sqns := make([]uint64, 0, n)
+	seq := m.seq(sqnHe)
+	for i := 0; i < n; i++ {
+		seq = m.nextSeq(seq)
+		sqns = append(sqns, m.sqn(seq, ind))
+	}
+	return sqns, m.sqn(seq, ind)
+}
+
+// accepted tells whether the USIM accepts seq after having accepted seqMs at
+// most, Annex C.2.1.
+func (m *SqnManager) accepted(seq, seqMs uint64) bool {
+	return seq > seqMs && seq-seqMs <= m.delta
+}
+
+// Resync returns SQN_HE after a synchronisation failure reporting sqnMs, clause
+// 6.3.5. The AUTS carrying sqnMs has passed MAC-S, so SQN_HE is reset to SQN_MS
+// unless the next SEQ is accepted by the USIM anyway, as is the case when
+// vectors were used out of order.
+func (m *SqnManager) Resync(sqnHe, sqnMs uint64) uint64 {
+	if m.accepted(m.nextSeq(m.seq(sqnHe)), m.seq(sqnMs)) {
+		return sqnHe
+	}
+	return sqnMs & SqnMask
+}
+
+// Stale tells whether sqnMs lags sqnHe by the freshness limit or more, which
+// the USIM would not have accepted, Annex C.2.2. Resync rolls SQN_HE back
+// regardless, this is only worth a warning.
+func (m *SqnManager) Stale(sqnHe, sqnMs uint64) bool {
+	seqHe, seqMs := m.seq(sqnHe), m.seq(sqnMs)
+	return seqHe > seqMs && m.freshnessLimit != 0 && seqHe-seqMs >= m.freshnessLimit
+}
+
+// IndKey encodes a serving network name or AUSF instance id as a key of
+// SequenceNumber.LastIndexes. Names contain '.' and ':', which are not usable in
+// document field names, so they are hex encoded.
+func IndKey(key string) string {
+	return hex.EncodeToString([]byte(key))
+}
+
+// AllocateInd returns the IND of the serving network or AUSF key from
+// lastIndexes, allocating an unused one when there is none. Once all INDs are
+// taken, the IND of the least recently used key is reallocated. lastIndexes is
+// updated with the allocation and lastUse, the time each key was last used, with
+// the use.
+func (m *SqnManager) AllocateInd(lastIndexes map[string]int32, lastUse map[string]time.Time, key string) int32 {
+	key = IndKey(key)
+	lastUse[key] = m.now()
+	size := int32(1) << m.indLength
+	if ind, ok := lastIndexes[key]; ok && ind >= 0 && ind < size {
+		return ind
+	}
+	delete(lastIndexes, key)
+
+	used := make(map[int32]bool, len(lastIndexes))
+	for _, ind := range lastIndexes {
+		used[ind] = true
+	}
+	for ind := int32(0); ind < size; ind++ {
+		if !used[ind] {
+			lastIndexes[key] = ind
+			return ind
+		}
+	}
+
+	// all INDs are taken, keys never seen used go first
+	lru := ""
+	for k := range lastIndexes {
+		if lru == "" || lastUse[k].Before(lastUse[lru]) || lastUse[k].Equal(lastUse[lru]) && k < lru {
+			lru = k
+		}
+	}
+	ind := lastIndexes[lru]
+	for k, i := range lastIndexes {
+		if i == ind {
+			delete(lastIndexes, k)
+			delete(lastUse, k)
+		}
+	}
+	lastIndexes[key] = ind
+	return ind
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/pkg/factory"
+)
+
+func newTestSqnManager(t *testing.T, cfg factory.SqnManagement, now time.Time) *SqnManager {
+	require.NoError(t, InitSqnManagement(&cfg))
+	t.Cleanup(func() {
+		require.NoError(t, InitSqnManagement(nil))
+	})
+	m := GetSqnManager()
+	m.now = func() time.Time { return now }
+	return m
+}
+
+func TestSqnGenerate(t *testing.T) {
+	now := time.Unix(0x123456, 0)
+
+	// profile 2: SEQ counts per vector, IND in the 5 low bits
+	m := newTestSqnManager(t, factory.SqnManagement{Profile: SqnProfileNonTimeBased}, now)
+	require.Equal(t, models.SqnScheme_NON_TIME_BASED, m.SqnScheme())
+	sqns, sqnHe := m.Generate(0x20<<5|3, 7, 3)
+	require.Equal(t, []uint64{0x21<<5 | 7, 0x22<<5 | 7, 0x23<<5 | 7}, sqns)
+	require.Equal(t, uint64(0x23<<5|7), sqnHe)
+
+	// profile 3: SEQ follows the clock, counting on within the same time unit
+	m = newTestSqnManager(t, factory.SqnManagement{Profile: SqnProfileTimeBased, IndLength: 4}, now)
+	sqns, _ = m.Generate(0x20<<4, 1, 2)
+	require.Equal(t, []uint64{0x123456<<4 | 1, 0x123457<<4 | 1}, sqns)
+	sqns, _ = m.Generate(0x200000<<4, 1, 1)
+	require.Equal(t, []uint64{0x200001<<4 | 1}, sqns)
+
+	// profile 1: SEQ2 follows the clock, SEQ1 is kept
+	m = newTestSqnManager(t, factory.SqnManagement{Profile: SqnProfilePartlyTimeBased}, now)
+	sqns, _ = m.Generate((0x5<<24|0x100)<<5, 0, 1)
+	require.Equal(t, []uint64{(0x5<<24 | 0x123456) << 5}, sqns)
+	sqns, _ = m.Generate((0x5<<24|0xffffff)<<5, 0, 1)
+	require.Equal(t, []uint64{(0x6 << 24) << 5}, sqns)
+}
+
+func TestSqnResync(t *testing.T) {
+	m := newTestSqnManager(t, factory.SqnManagement{
+		Profile:        SqnProfileNonTimeBased,
+		Delta:          0x100,
+		FreshnessLimit: 0x1000,
+	}, time.Now())
+
+	testCases := []struct {
+		name        string
+		sqnHe       uint64
+		sqnMs       uint64
+		expectSqn   uint64
+		expectStale bool
+	}{
+		{
+			name:      "Next SEQ accepted, vectors used out of order",
+			sqnHe:     0x50<<5 | 1,
+			sqnMs:     0x40<<5 | 2,
+			expectSqn: 0x50<<5 | 1,
+		},
+		{
+			name:      "USIM ahead",
+			sqnHe:     0x50<<5 | 1,
+			sqnMs:     0x60<<5 | 2,
+			expectSqn: 0x60<<5 | 2,
+		},
+		{
+			name:      "HE ahead by more than delta",
+			sqnHe:     0x500<<5 | 1,
+			sqnMs:     0x100<<5 | 2,
+			expectSqn: 0x100<<5 | 2,
+		},
+		{
+			name:        "SQN_MS outside freshness limit",
+			sqnHe:       0x2000<<5 | 1,
+			sqnMs:       0x100<<5 | 2,
+			expectSqn:   0x100<<5 | 2,
+			expectStale: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectSqn, m.Resync(tc.sqnHe, tc.sqnMs))
+			require.Equal(t, tc.expectStale, m.Stale(tc.sqnHe, tc.sqnMs))
+		})
+	}
+}
+
+func TestSqnAllocateInd(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := newTestSqnManager(t, factory.SqnManagement{Profile: SqnProfileNonTimeBased, IndLength: 1}, now)
+	m.now = func() time.Time { return now }
+	snA, snB, snC := "5G:mnc093.mcc208.3gppnetwork.org", "5G:mnc410.mcc310.3gppnetwork.org",
+		"5G:mnc001.mcc001.3gppnetwork.org"
+	require.Equal(t, "35473a6d6e633039332e6d63633230382e336770706e6574776f726b2e6f7267", IndKey(snA))
+
+	lastIndexes := map[string]int32{IndKey(snA): 0}
+	lastUse := map[string]time.Time{}
+	require.Equal(t, int32(0), m.AllocateInd(lastIndexes, lastUse, snA))
+	now = now.Add(time.Second)
+	require.Equal(t, int32(1), m.AllocateInd(lastIndexes, lastUse, snB))
+	require.Equal(t, int32(1), m.AllocateInd(lastIndexes, lastUse, snB))
+	require.Equal(t, map[string]int32{IndKey(snA): 0, IndKey(snB): 1}, lastIndexes)
+
+	// INDs exhausted, the least recently used one is reallocated
+	now = now.Add(time.Second)
+	require.Equal(t, int32(0), m.AllocateInd(lastIndexes, lastUse, snC))
+	require.Equal(t, map[string]int32{IndKey(snB): 1, IndKey(snC): 0}, lastIndexes)
+	now = now.Add(time.Second)
+	require.Equal(t, int32(1), m.AllocateInd(lastIndexes, lastUse, snA))
+	require.Equal(t, map[string]int32{IndKey(snA): 1, IndKey(snC): 0}, lastIndexes)
+
+	// keys without a known use, e.g. allocated by another UDM, go first
+	lastIndexes = map[string]int32{IndKey(snA): 0, IndKey(snB): 1}
+	lastUse = map[string]time.Time{IndKey(snA): now}
+	require.Equal(t, int32(1), m.AllocateInd(lastIndexes, lastUse, snC))
+}
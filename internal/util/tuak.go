@@ -0,0 +1,280 @@
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// TUAK algorithm set, 3GPP TS 35.231. All functions share the Keccak-f[1600]
+// permutation over a 200-octet INOUT state whose layout is fixed by the spec:
+//
+//	[0:32]   TOP or TOPc
+//	[32]     INSTANCE
+//	[33:40]  ALGONAME ("TUAK1.0")
+//	[40:56]  RAND
+//	[56:58]  AMF
+//	[58:64]  SQN
+//	[64:96]  KEY (128-bit K is zero extended)
+//	[96]     padding 0x1F
+//	[135]    padding 0x80
+//
+// Every field is written into (and read back from) the state in reversed octet order.
+
+const (
+	TuakTopLen                  = 32
+	TuakDefaultKeccakIterations = 1
+)
+
+const (
+	tuakStateLen = 200
+	tuakAlgoName = "TUAK1.0"
+)
+
+// INSTANCE values, TS 35.231 clause 6.
+const (
+	tuakInstanceF1     byte = 0x00
+	tuakInstanceF1Star byte = 0x80
+	tuakInstanceF2345  byte = 0x40
+	tuakInstanceF5Star byte = 0xc0
+	tuakInstanceKey256 byte = 0x01
+	tuakInstanceCk256  byte = 0x04
+	tuakInstanceIk256  byte = 0x02
+)
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// keccakF1600 applies the Keccak-f[1600] permutation to the state a, where
+// lane (x, y) is stored at a[x+5*y].
+func keccakF1600(a *[25]uint64) {
+	var b [25]uint64
+	var c, d [5]uint64
+	for round := 0; round < 24; round++ {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d[x] = c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+		}
+		for i := 0; i < 25; i++ {
+			a[i] ^= d[i%5]
+		}
+		// rho and pi
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+		// chi
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 5; x++ {
+				a[x+5*y] = b[x+5*y] ^ (^b[(x+1)%5+5*y] & b[(x+2)%5+5*y])
+			}
+		}
+		// iota
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
+
+func keccakPermute(state []byte) {
+	var lanes [25]uint64
+	for i := range lanes {
+		lanes[i] = binary.LittleEndian.Uint64(state[8*i:])
+	}
+	keccakF1600(&lanes)
+	for i := range lanes {
+		binary.LittleEndian.PutUint64(state[8*i:], lanes[i])
+	}
+}
+
+func tuakPush(state []byte, offset int, data []byte) {
+	for i := range data {
+		state[offset+i] = data[len(data)-1-i]
+	}
+}
+
+func tuakPop(out, state []byte, offset int) {
+	for i := range out {
+		out[i] = state[offset+len(out)-1-i]
+	}
+}
+
+func tuakKeyInstance(k []byte) (byte, error) {
+	switch len(k) {
+	case 16:
+		return 0, nil
+	case 32:
+		return tuakInstanceKey256, nil
+	default:
+		return 0, fmt.Errorf("TUAK: K must be 128 or 256 bits, got %d", len(k)*8)
+	}
+}
+
+func tuakMain(topc []byte, instance byte, rand, amf, sqn, k []byte, keccakIterations int) ([]byte, error) {
+	if len(topc) != TuakTopLen {
+		return nil, fmt.Errorf("TUAK: TOPc must be 256 bits, got %d", len(topc)*8)
+	}
+	if len(rand) != 16 {
+		return nil, fmt.Errorf("TUAK: RAND must be 128 bits, got %d", len(rand)*8)
+	}
+	if keccakIterations < 1 {
+		keccakIterations = TuakDefaultKeccakIterations
+	}
+
+	state := make([]byte, tuakStateLen)
+	tuakPush(state, 0, topc)
+	state[32] = instance
+	tuakPush(state, 33, []byte(tuakAlgoName))
+	tuakPush(state, 40, rand)
+	if amf != nil {
+		tuakPush(state, 56, amf)
+	}
+	if sqn != nil {
+		tuakPush(state, 58, sqn)
+	}
+	tuakPush(state, 64, k)
+	state[96] = 0x1f
+	state[135] = 0x80
+
+	for i := 0; i < keccakIterations; i++ {
+		keccakPermute(state)
+	}
+	return state, nil
+}
+
+// TuakTopc derives TOPc from TOP and K.
+func TuakTopc(top, k []byte, keccakIterations int) ([]byte, error) {
+	instance, err := tuakKeyInstance(k)
+	if err != nil {
+		return nil, err
+	}
+	state, err := tuakMain(top, instance, make([]byte, 16), nil, nil, k, keccakIterations)
+	if err != nil {
+		return nil, err
+	}
+	topc := make([]byte, TuakTopLen)
+	tuakPop(topc, state, 0)
+	return topc, nil
+}
+
+func tuakMacInstance(macLen int) (byte, error) {
+	switch macLen {
+	case 8:
+		return 0x08, nil
+	case 16:
+		return 0x10, nil
+	case 32:
+		return 0x20, nil
+	default:
+		return 0, fmt.Errorf("TUAK: MAC must be 64, 128 or 256 bits, got %d", macLen*8)
+	}
+}
+
+// TuakF1 computes MAC-A (f1) and MAC-S (f1*). The length of macA and macS
+// selects the MAC length; either may be nil.
+func TuakF1(topc, k, rand, sqn, amf []byte, macA, macS []byte, keccakIterations int) error {
+	keyInstance, err := tuakKeyInstance(k)
+	if err != nil {
+		return err
+	}
+	if len(sqn) != 6 || len(amf) != 2 {
+		return fmt.Errorf("TUAK: SQN must be 48 bits and AMF 16 bits")
+	}
+
+	outputs := []struct {
+		base byte
+		mac  []byte
+	}{
+		{tuakInstanceF1, macA},
+		{tuakInstanceF1Star, macS},
+	}
+	for _, o := range outputs {
+		if o.mac == nil {
+			continue
+		}
+		macInstance, err := tuakMacInstance(len(o.mac))
+		if err != nil {
+			return err
+		}
+		state, err := tuakMain(topc, o.base|macInstance|keyInstance, rand, amf, sqn, k, keccakIterations)
+		if err != nil {
+			return err
+		}
+		tuakPop(o.mac, state, 0)
+	}
+	return nil
+}
+
+// TuakF2345 computes RES (f2), CK (f3), IK (f4), AK (f5) and AK* (f5*).
+// The length of res, ck and ik selects their size; any output may be nil.
+func TuakF2345(topc, k, rand []byte, res, ck, ik, ak, akstar []byte, keccakIterations int) error {
+	keyInstance, err := tuakKeyInstance(k)
+	if err != nil {
+		return err
+	}
+
+	if res != nil || ck != nil || ik != nil || ak != nil {
+		instance := tuakInstanceF2345 | keyInstance
+		switch len(res) {
+		case 0, 4:
+		case 8:
+			instance |= 0x08
+		case 16:
+			instance |= 0x10
+		case 32:
+			instance |= 0x20
+		default:
+			return fmt.Errorf("TUAK: RES must be 32, 64, 128 or 256 bits, got %d", len(res)*8)
+		}
+		switch len(ck) {
+		case 0, 16:
+		case 32:
+			instance |= tuakInstanceCk256
+		default:
+			return fmt.Errorf("TUAK: CK must be 128 or 256 bits, got %d", len(ck)*8)
+		}
+		switch len(ik) {
+		case 0, 16:
+		case 32:
+			instance |= tuakInstanceIk256
+		default:
+			return fmt.Errorf("TUAK: IK must be 128 or 256 bits, got %d", len(ik)*8)
+		}
+
+		state, err := tuakMain(topc, instance, rand, nil, nil, k, keccakIterations)
+		if err != nil {
+			return err
+		}
+		tuakPop(res, state, 0)
+		tuakPop(ck, state, 32)
+		tuakPop(ik, state, 64)
+		if ak != nil {
+			tuakPop(ak[:6], state, 96)
+		}
+	}
+
+	if akstar != nil {
+		state, err := tuakMain(topc, tuakInstanceF5Star|keyInstance, rand, nil, nil, k, keccakIterations)
+		if err != nil {
+			return err
+		}
+		tuakPop(akstar[:6], state, 96)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"crypto/sha3"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeccakF1600(t *testing.T) {
+	// SHA3-256("abc") is a single absorb of one padded block
+	msg := []byte("abc")
+	state := make([]byte, tuakStateLen)
+	copy(state, msg)
+	state[len(msg)] ^= 0x06
+	state[135] ^= 0x80
+	keccakPermute(state)
+
+	expected := sha3.Sum256(msg)
+	require.Equal(t, expected[:], state[:32])
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// 3GPP TS 35.232 test set 1
+func TestTuakTestSet1(t *testing.T) {
+	top := decodeHex(t, "5555555555555555555555555555555555555555555555555555555555555555")
+	k := decodeHex(t, "abababababababababababababababab")
+	rand := decodeHex(t, "42424242424242424242424242424242")
+	sqn := decodeHex(t, "111111111111")
+	amf := decodeHex(t, "ffff")
+
+	topc, err := TuakTopc(top, k, 1)
+	require.NoError(t, err)
+	require.Equal(t, "bd04d9530e87513c5d837ac2ad954623a8e2330c115305a73eb45d1f40cccbff", hex.EncodeToString(topc))
+
+	macA, macS := make([]byte, 8), make([]byte, 8)
+	require.NoError(t, TuakF1(topc, k, rand, sqn, amf, macA, macS, 1))
+	require.Equal(t, "f9a54e6aeaa8618d", hex.EncodeToString(macA))
+	require.Equal(t, "e94b4dc6c7297df3", hex.EncodeToString(macS))
+
+	res, ck, ik := make([]byte, 4), make([]byte, 16), make([]byte, 16)
+	ak, akstar := make([]byte, 6), make([]byte, 6)
+	require.NoError(t, TuakF2345(topc, k, rand, res, ck, ik, ak, akstar, 1))
+	require.Equal(t, "657acd64", hex.EncodeToString(res))
+	require.Equal(t, "d71a1e5c6caffe986a26f783e5c78be1", hex.EncodeToString(ck))
+	require.Equal(t, "be849fa2564f869aecee6f62d4337e72", hex.EncodeToString(ik))
+	require.Equal(t, "719f1e9b9054", hex.EncodeToString(ak))
+	require.Equal(t, "e7af6b3d0e38", hex.EncodeToString(akstar))
+}
+
+func TestTuakInvalidLength(t *testing.T) {
+	topc := make([]byte, TuakTopLen)
+	rand := make([]byte, 16)
+	sqn, amf := make([]byte, 6), make([]byte, 2)
+
+	require.Error(t, TuakF1(topc, make([]byte, 24), rand, sqn, amf, make([]byte, 8), nil, 1))
+	require.Error(t, TuakF1(topc, make([]byte, 16), rand, sqn, amf, make([]byte, 12), nil, 1))
+	require.Error(t, TuakF2345(topc, make([]byte, 32), rand, make([]byte, 6), nil, nil, nil, nil, 1))
+	require.NoError(t, TuakF2345(topc, make([]byte, 32), rand, make([]byte, 32), make([]byte, 32),
+		make([]byte, 32), make([]byte, 6), nil, 1))
+}
@@ -7,12 +7,16 @@ package factory
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 
+	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/keybackend"
 	"github.com/free5gc/udm/pkg/suci"
 )
 
@@ -44,6 +48,50 @@ const (
 	UdmRsdsResUriPrefix           = "/nudm-rsds/v1"
 	UdmSsauResUriPrefix           = "/nudm-ssau/v1"
 	UdmUeidResUriPrefix           = "/nudm-ueid/v1"
+	UdmAdminResUriPrefix          = "/udm-admin/v1"
+)
+
+// TUAK output lengths in bits and Keccak iterations, 3GPP TS 35.231.
+const (
+	TuakDefaultMacLength        = 64
+	TuakDefaultResLength        = 64
+	TuakDefaultCkLength         = 128
+	TuakDefaultIkLength         = 128
+	TuakDefaultKeccakIterations = 1
+)
+
+// SQN management defaults, 3GPP TS 33.102 Annex C.3.
+const (
+	SqnDefaultIndLength     = 5
+	SqnDefaultDelta         = 1 << 28
+	SqnDefaultTimeUnit      = "1s"
+	SqnIndByServingNetwork  = "servingNetwork"
+	SqnIndByAusf            = "ausf"
+	SqnDefaultIndAllocation = SqnIndByServingNetwork
+)
+
+const (
+	AuthVectorPoolDefaultSize     = 5
+	AuthVectorPoolDefaultLifetime = "5m"
+)
+
+const AdminDefaultSqnAuditSize = 1024
+
+const (
+	AuthLockoutDefaultMaxFailures = 5
+	AuthLockoutDefaultWindow      = "10m"
+	AuthLockoutDefaultDuration    = "30m"
+)
+
+const (
+	SuciCacheDefaultSize = 10000
+	SuciCacheDefaultTtl  = "30s"
+)
+
+// Algorithms of the key encryption keys protecting subscriber keys in the UDR
+const (
+	KekAlgorithmAes256Gcm = keybackend.UnwrapAes256Gcm
+	KekAlgorithmAesKw     = keybackend.UnwrapAesKw
 )
 
 type Config struct {
@@ -76,6 +124,29 @@ type Configuration struct {
 	NrfUri          string             `yaml:"nrfUri,omitempty"  valid:"required, url"`
 	NrfCertPem      string             `yaml:"nrfCertPem,omitempty" valid:"optional"`
 	SuciProfiles    []suci.SuciProfile `yaml:"SuciProfile,omitempty"`
+	Tuak            *Tuak              `yaml:"tuak,omitempty" valid:"optional"`
+	// OP and TOP for subscriptions provisioned without OPc or TOPc
+	OperatorKeys *OperatorKeys `yaml:"operatorKeys,omitempty" valid:"optional"`
+	// KEKs referenced by AuthenticationSubscription.ProtectionParameterId
+	KeyEncryptionKeys []KeyEncryptionKey `yaml:"keyEncryptionKeys,omitempty" valid:"optional"`
+	// Accept subscriber keys without protectionParameterId as plaintext while
+	// keyEncryptionKeys are configured, e.g. while migrating the UDR
+	AllowPlaintextKeys bool `yaml:"allowPlaintextKeys,omitempty" valid:"optional"`
+	// Token holding SuciProfile private keys and KEKs with keyBackend pkcs11
+	Pkcs11 *keybackend.Pkcs11Config `yaml:"pkcs11,omitempty" valid:"optional"`
+	// Without sqnManagement the SQN is a plain 48-bit counter
+	SqnManagement *SqnManagement `yaml:"sqnManagement,omitempty" valid:"optional"`
+	// Without authVectorPool each vector is generated on request
+	AuthVectorPool *AuthVectorPool `yaml:"authVectorPool,omitempty" valid:"optional"`
+	Admin          *Admin          `yaml:"admin,omitempty" valid:"optional"`
+	// Routing indicators of the SUCIs this UDM de-conceals, any if empty
+	RoutingIndicators []string `yaml:"routingIndicators,omitempty" valid:"optional"`
+	// PLMNs of the serving networks vectors are generated for, any if empty
+	AllowedPlmnList []models.PlmnId `yaml:"allowedPlmnList,omitempty" valid:"optional"`
+	// Without authLockout authentication failures never block a subscriber
+	AuthLockout *AuthLockout `yaml:"authLockout,omitempty" valid:"optional"`
+	// Without suciCache each SUCI is de-concealed on request
+	SuciCache *SuciCache `yaml:"suciCache,omitempty" valid:"optional"`
 }
 type Logger struct {
 	Enable       bool   `yaml:"enable" valid:"type(bool)"`
@@ -111,9 +182,10 @@ func (c *Configuration) validate() (bool, error) {
 	if c.ServiceNameList != nil {
 		var errs govalidator.Errors
 		for _, v := range c.ServiceNameList {
-			if v != "nudm-sdm" && v != "nudm-uecm" && v != "nudm-ueau" && v != "nudm-ee" && v != "nudm-pp" {
+			if v != "nudm-sdm" && v != "nudm-uecm" && v != "nudm-ueau" && v != "nudm-ee" && v != "nudm-pp" &&
+				v != "nudm-ueid" {
 				err := fmt.Errorf("invalid ServiceNameList: [%s],"+
-					" value should be nudm-sdm or nudm-uecm or nudm-ueau or nudm-ee or nudm-pp", v)
+					" value should be nudm-sdm or nudm-uecm or nudm-ueau or nudm-ee or nudm-pp or nudm-ueid", v)
 				errs = append(errs, err)
 			}
 		}
@@ -124,23 +196,54 @@ func (c *Configuration) validate() (bool, error) {
 
 	if c.SuciProfiles != nil {
 		var errs govalidator.Errors
-		for _, s := range c.SuciProfiles {
+		keyIds := make(map[int]bool)
+		for i, s := range c.SuciProfiles {
+			keyId := s.GetKeyId(i)
+			if keyId < 1 || keyId > 255 {
+				errs = append(errs, fmt.Errorf("invalid KeyId: %d, should be in 1-255", keyId))
+			} else if keyIds[keyId] {
+				errs = append(errs, fmt.Errorf("duplicate SuciProfile KeyId: %d", keyId))
+			}
+			keyIds[keyId] = true
+
+			if notBefore, notAfter, err := s.Validity(); err != nil {
+				errs = append(errs, fmt.Errorf("invalid SuciProfile %d: %w", keyId, err))
+			} else if !notBefore.IsZero() && !notAfter.IsZero() && !notBefore.Before(notAfter) {
+				errs = append(errs, fmt.Errorf("invalid SuciProfile %d: NotBefore is not before NotAfter", keyId))
+			}
+
 			protectScheme := s.ProtectionScheme
 			if result := govalidator.StringMatches(protectScheme, "^[A-F0-9]{1}$"); !result {
 				err := fmt.Errorf("invalid ProtectionScheme: %s, should be a single hexadecimal digit", protectScheme)
 				errs = append(errs, err)
+				continue
+			}
+			scheme, ok := suci.LookupScheme(protectScheme)
+			if !ok {
+				errs = append(errs, fmt.Errorf("invalid ProtectionScheme: %s, not supported", protectScheme))
+				continue
 			}
 
-			privateKey := s.PrivateKey
-			if result := govalidator.StringMatches(privateKey, "^[A-Fa-f0-9]{64}$"); !result {
-				err := fmt.Errorf("invalid PrivateKey: %s, should be 64 hexadecimal digits", privateKey)
-				errs = append(errs, err)
+			if s.KeyBackend != "" {
+				if !scheme.KeyBackend {
+					errs = append(errs, fmt.Errorf("invalid SuciProfile: %s keys cannot be held by a key backend",
+						scheme.Name))
+				} else if err := c.validateKeyBackend(s.KeyBackend, s.KeyLabel); err != nil {
+					errs = append(errs, fmt.Errorf("invalid SuciProfile: %w", err))
+				}
+			} else {
+				privateKey := s.PrivateKey
+				if !isHexKey(privateKey, scheme.PrivateKeyLen) {
+					err := fmt.Errorf("invalid PrivateKey: %s, should be %d hexadecimal digits (%s)",
+						privateKey, 2*scheme.PrivateKeyLen, scheme.Name)
+					errs = append(errs, err)
+				}
 			}
 
 			publicKey := s.PublicKey
-			if result := govalidator.StringMatches(publicKey, "^[A-Fa-f0-9]{64,130}$"); !result {
-				err := fmt.Errorf("invalid PublicKey: %s, should be 64(profile A), 66(profile B, compressed),"+
-					"or 130(profile B, uncompressed) hexadecimal digits", publicKey)
+			if !slices.ContainsFunc(scheme.PublicKeyLens, func(n int) bool { return isHexKey(publicKey, n) }) {
+				err := fmt.Errorf("invalid PublicKey: %s, should be %v octets as hexadecimal digits (%s)",
+					publicKey, scheme.PublicKeyLens, scheme.Name)
 				errs = append(errs, err)
 			}
 		}
@@ -149,10 +252,118 @@ func (c *Configuration) validate() (bool, error) {
 		}
 	}
 
+	if c.Tuak != nil {
+		if result, err := c.Tuak.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if c.OperatorKeys != nil {
+		if result, err := c.OperatorKeys.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if c.KeyEncryptionKeys != nil {
+		var errs govalidator.Errors
+		keyIds := make(map[string]bool)
+		for _, kek := range c.KeyEncryptionKeys {
+			if _, err := kek.validate(); err != nil {
+				errs = append(errs, err)
+			}
+			if kek.KeyBackend != "" {
+				if err := c.validateKeyBackend(kek.KeyBackend, kek.KeyLabel); err != nil {
+					errs = append(errs, fmt.Errorf("invalid KeyEncryptionKey [%s]: %w", kek.KeyId, err))
+				}
+			}
+			if keyIds[kek.KeyId] {
+				errs = append(errs, fmt.Errorf("duplicate KeyEncryptionKey keyId: %s", kek.KeyId))
+			}
+			keyIds[kek.KeyId] = true
+		}
+		if len(errs) > 0 {
+			return false, error(errs)
+		}
+	}
+
+	if c.SqnManagement != nil {
+		if result, err := c.SqnManagement.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if c.AuthVectorPool != nil {
+		if result, err := c.AuthVectorPool.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if c.SuciCache != nil {
+		if result, err := c.SuciCache.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if c.RoutingIndicators != nil {
+		var errs govalidator.Errors
+		for _, routingIndicator := range c.RoutingIndicators {
+			if !govalidator.StringMatches(routingIndicator, "^[0-9]{1,4}$") {
+				err := fmt.Errorf("invalid routingIndicator: %s, should be 1-4 digits", routingIndicator)
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return false, error(errs)
+		}
+	}
+
+	if c.AllowedPlmnList != nil {
+		var errs govalidator.Errors
+		for _, plmn := range c.AllowedPlmnList {
+			if !govalidator.StringMatches(plmn.Mcc, "^[0-9]{3}$") || !govalidator.StringMatches(plmn.Mnc, "^[0-9]{2,3}$") {
+				err := fmt.Errorf("invalid allowedPlmnList entry: mcc %s, mnc %s", plmn.Mcc, plmn.Mnc)
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return false, error(errs)
+		}
+	}
+
+	if c.AuthLockout != nil {
+		if result, err := c.AuthLockout.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if c.Admin != nil {
+		if result, err := c.Admin.validate(); err != nil {
+			return result, err
+		}
+	}
+
 	result, err := govalidator.ValidateStruct(c)
 	return result, err
 }
 
+// isHexKey tells whether key is n octets as hexadecimal digits
+func isHexKey(key string, n int) bool {
+	return len(key) == 2*n && govalidator.IsHexadecimal(key)
+}
+
+func (c *Configuration) validateKeyBackend(backend, keyLabel string) error {
+	if backend != keybackend.Pkcs11 {
+		return fmt.Errorf("unknown keyBackend: %s, should be %s", backend, keybackend.Pkcs11)
+	}
+	if c.Pkcs11 == nil {
+		return fmt.Errorf("keyBackend %s requires the pkcs11 configuration", backend)
+	}
+	if keyLabel == "" {
+		return fmt.Errorf("keyBackend %s requires a key label", backend)
+	}
+	return nil
+}
+
 func (c *Config) GetCertPemPath() string {
 	c.RLock()
 	defer c.RUnlock()
@@ -195,6 +406,294 @@ func (t *Tls) validate() (bool, error) {
 	return result, err
 }
 
+// Tuak holds the TUAK parameters used for subscribers whose algorithmId is "tuak".
+// The MAC, RES, CK and IK lengths are given in bits.
+type Tuak struct {
+	MacLength        int `yaml:"macLength,omitempty" valid:"optional,in(64|128|256)"`
+	ResLength        int `yaml:"resLength,omitempty" valid:"optional,in(32|64|128|256)"`
+	CkLength         int `yaml:"ckLength,omitempty" valid:"optional,in(128|256)"`
+	IkLength         int `yaml:"ikLength,omitempty" valid:"optional,in(128|256)"`
+	KeccakIterations int `yaml:"keccakIterations,omitempty" valid:"optional,range(1|255)"`
+}
+
+func (t *Tuak) validate() (bool, error) {
+	result, err := govalidator.ValidateStruct(t)
+	return result, err
+}
+
+func (t *Tuak) GetMacLength() int {
+	if t != nil && t.MacLength != 0 {
+		return t.MacLength
+	}
+	return TuakDefaultMacLength
+}
+
+func (t *Tuak) GetResLength() int {
+	if t != nil && t.ResLength != 0 {
+		return t.ResLength
+	}
+	return TuakDefaultResLength
+}
+
+func (t *Tuak) GetCkLength() int {
+	if t != nil && t.CkLength != 0 {
+		return t.CkLength
+	}
+	return TuakDefaultCkLength
+}
+
+func (t *Tuak) GetIkLength() int {
+	if t != nil && t.IkLength != 0 {
+		return t.IkLength
+	}
+	return TuakDefaultIkLength
+}
+
+func (t *Tuak) GetKeccakIterations() int {
+	if t != nil && t.KeccakIterations != 0 {
+		return t.KeccakIterations
+	}
+	return TuakDefaultKeccakIterations
+}
+
+// OperatorKeys holds the operator variant algorithm configuration fields the
+// OPc (MILENAGE) and TOPc (TUAK) of a subscription are derived from when the
+// subscription does not carry them.
+type OperatorKeys struct {
+	// MILENAGE OP, 128 bits as hexadecimal digits
+	Op string `yaml:"op,omitempty" valid:"optional"`
+	// TUAK TOP, 256 bits as hexadecimal digits
+	Top string `yaml:"top,omitempty" valid:"optional"`
+}
+
+func (o *OperatorKeys) validate() (bool, error) {
+	if o.Op != "" && !isHexKey(o.Op, 16) {
+		return false, fmt.Errorf("invalid operatorKeys op, should be 16 octets as hexadecimal digits")
+	}
+	if o.Top != "" && !isHexKey(o.Top, 32) {
+		return false, fmt.Errorf("invalid operatorKeys top, should be 32 octets as hexadecimal digits")
+	}
+	return true, nil
+}
+
+// KeyEncryptionKey decrypts the subscriber keys whose protectionParameterId is
+// KeyId. AES-256-GCM keys are nonce || ciphertext || tag with the AAD
+// "<supi>|<field>", field being encPermanentKey, encOpcKey or encTopcKey.
+type KeyEncryptionKey struct {
+	KeyId     string `yaml:"keyId" valid:"required"`
+	Algorithm string `yaml:"algorithm" valid:"required,in(AES-256-GCM|AES-KW)"`
+	Key       string `yaml:"key,omitempty" valid:"optional,hexadecimal"`
+	// When KeyBackend is set, the KEK is held by that key backend under
+	// KeyLabel and Key is not used.
+	KeyBackend string `yaml:"keyBackend,omitempty" valid:"optional"`
+	KeyLabel   string `yaml:"keyLabel,omitempty" valid:"optional"`
+}
+
+func (k *KeyEncryptionKey) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(k); err != nil {
+		return false, err
+	}
+	if k.KeyBackend != "" {
+		return true, nil
+	}
+
+	keyLen := len(k.Key)
+	if k.Algorithm == KekAlgorithmAes256Gcm && keyLen != 64 {
+		return false, fmt.Errorf("invalid KeyEncryptionKey [%s]: AES-256-GCM key should be 64 hexadecimal digits",
+			k.KeyId)
+	}
+	if k.Algorithm == KekAlgorithmAesKw && keyLen != 32 && keyLen != 48 && keyLen != 64 {
+		return false, fmt.Errorf("invalid KeyEncryptionKey [%s]: AES-KW key should be 32, 48 or 64 hexadecimal digits",
+			k.KeyId)
+	}
+	return true, nil
+}
+
+// SqnManagement selects the SQN management of TS 33.102 Annex C. SQN is
+// SEQ || IND, with an IND of IndLength bits allocated per serving network or
+// AUSF. Profile 1 is partly time-based (C.3.1), profile 2 is not time-based
+// (C.3.2) and profile 3 is time-based (C.3.3), counting in TimeUnit. Delta and
+// FreshnessLimit are the USIM's limits of C.2.1 and C.2.2. A re-synchronisation
+// rolling SQN_HE back by FreshnessLimit or more is logged, 0 disables the warning.
+type SqnManagement struct {
+	Profile        int    `yaml:"profile" valid:"required,in(1|2|3)"`
+	IndLength      int    `yaml:"indLength,omitempty" valid:"optional,range(1|10)"`
+	Delta          uint64 `yaml:"delta,omitempty" valid:"optional"`
+	FreshnessLimit uint64 `yaml:"freshnessLimit,omitempty" valid:"optional"`
+	TimeUnit       string `yaml:"timeUnit,omitempty" valid:"optional"`
+	IndAllocation  string `yaml:"indAllocation,omitempty" valid:"optional,in(servingNetwork|ausf)"`
+}
+
+func (s *SqnManagement) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(s); err != nil {
+		return false, err
+	}
+	if unit, err := time.ParseDuration(s.GetTimeUnit()); err != nil || unit <= 0 {
+		return false, fmt.Errorf("invalid sqnManagement timeUnit: %s, should be a positive duration", s.TimeUnit)
+	}
+	return true, nil
+}
+
+func (s *SqnManagement) GetIndLength() int {
+	if s.IndLength != 0 {
+		return s.IndLength
+	}
+	return SqnDefaultIndLength
+}
+
+func (s *SqnManagement) GetDelta() uint64 {
+	if s.Delta != 0 {
+		return s.Delta
+	}
+	return SqnDefaultDelta
+}
+
+func (s *SqnManagement) GetTimeUnit() string {
+	if s.TimeUnit != "" {
+		return s.TimeUnit
+	}
+	return SqnDefaultTimeUnit
+}
+
+func (s *SqnManagement) GetIndAllocation() string {
+	if s.IndAllocation != "" {
+		return s.IndAllocation
+	}
+	return SqnDefaultIndAllocation
+}
+
+// AuthVectorPool generates Size vectors with a single SQN update in UDR and
+// keeps the ones not handed out for up to Lifetime.
+type AuthVectorPool struct {
+	Size     int    `yaml:"size,omitempty" valid:"optional,range(2|32)"`
+	Lifetime string `yaml:"lifetime,omitempty" valid:"optional"`
+}
+
+func (a *AuthVectorPool) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(a); err != nil {
+		return false, err
+	}
+	if lifetime, err := time.ParseDuration(a.GetLifetime()); err != nil || lifetime <= 0 {
+		return false, fmt.Errorf("invalid authVectorPool lifetime: %s, should be a positive duration", a.Lifetime)
+	}
+	return true, nil
+}
+
+func (a *AuthVectorPool) GetSize() int {
+	if a.Size != 0 {
+		return a.Size
+	}
+	return AuthVectorPoolDefaultSize
+}
+
+func (a *AuthVectorPool) GetLifetime() string {
+	if a.Lifetime != "" {
+		return a.Lifetime
+	}
+	return AuthVectorPoolDefaultLifetime
+}
+
+// AuthLockout blocks vector generation for a subscriber for Duration once
+// MaxFailures failed re-synchronisations or rejected authentication
+// confirmations happened within Window.
+type AuthLockout struct {
+	MaxFailures int    `yaml:"maxFailures,omitempty" valid:"optional,range(1|1000)"`
+	Window      string `yaml:"window,omitempty" valid:"optional"`
+	Duration    string `yaml:"duration,omitempty" valid:"optional"`
+}
+
+func (a *AuthLockout) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(a); err != nil {
+		return false, err
+	}
+	for name, value := range map[string]string{"window": a.GetWindow(), "duration": a.GetDuration()} {
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return false, fmt.Errorf("invalid authLockout %s: %s, should be a positive duration", name, value)
+		}
+	}
+	return true, nil
+}
+
+func (a *AuthLockout) GetMaxFailures() int {
+	if a.MaxFailures != 0 {
+		return a.MaxFailures
+	}
+	return AuthLockoutDefaultMaxFailures
+}
+
+func (a *AuthLockout) GetWindow() string {
+	if a.Window != "" {
+		return a.Window
+	}
+	return AuthLockoutDefaultWindow
+}
+
+func (a *AuthLockout) GetDuration() string {
+	if a.Duration != "" {
+		return a.Duration
+	}
+	return AuthLockoutDefaultDuration
+}
+
+// SuciCache keeps the SUPIs of up to Size de-concealed SUCIs for Ttl, so a
+// SUCI presented again, e.g. on a retry or a re-synchronisation, is not
+// de-concealed again.
+type SuciCache struct {
+	Size int    `yaml:"size,omitempty" valid:"optional,range(1|1000000)"`
+	Ttl  string `yaml:"ttl,omitempty" valid:"optional"`
+}
+
+func (s *SuciCache) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(s); err != nil {
+		return false, err
+	}
+	if ttl, err := time.ParseDuration(s.GetTtl()); err != nil || ttl <= 0 {
+		return false, fmt.Errorf("invalid suciCache ttl: %s, should be a positive duration", s.Ttl)
+	}
+	return true, nil
+}
+
+func (s *SuciCache) GetSize() int {
+	if s.Size != 0 {
+		return s.Size
+	}
+	return SuciCacheDefaultSize
+}
+
+func (s *SuciCache) GetTtl() string {
+	if s.Ttl != "" {
+		return s.Ttl
+	}
+	return SuciCacheDefaultTtl
+}
+
+// Admin serves operational data of the UDM, e.g. the SQN audit trail, under
+// UdmAdminResUriPrefix on the SBI server.
+type Admin struct {
+	Enable bool `yaml:"enable" valid:"type(bool)"`
+	// Bearer token required from admin clients, mandatory when enabled
+	Token        string `yaml:"token,omitempty" valid:"optional"`
+	SqnAuditSize int    `yaml:"sqnAuditSize,omitempty" valid:"optional,range(1|1000000)"`
+}
+
+func (a *Admin) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(a); err != nil {
+		return false, err
+	}
+	// the admin API serves SUPIs and SQNs on the SBI server, never open
+	if a.Enable && a.Token == "" {
+		return false, fmt.Errorf("admin requires a token when enabled")
+	}
+	return true, nil
+}
+
+func (a *Admin) GetSqnAuditSize() int {
+	if a != nil && a.SqnAuditSize != 0 {
+		return a.SqnAuditSize
+	}
+	return AdminDefaultSqnAuditSize
+}
+
 type Metrics struct {
 	Enable      bool   `yaml:"enable" valid:"optional"`
 	Scheme      string `yaml:"scheme" valid:"required,scheme"`
@@ -365,6 +864,96 @@ func (c *Config) GetSbiScheme() string {
 	return UdmSbiDefaultScheme
 }
 
+func (c *Config) GetTuak() *Tuak {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.Tuak
+	}
+	return nil
+}
+
+func (c *Config) GetOperatorKeys() *OperatorKeys {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.OperatorKeys
+	}
+	return nil
+}
+
+func (c *Config) GetKeyEncryptionKeys() []KeyEncryptionKey {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.KeyEncryptionKeys
+	}
+	return nil
+}
+
+func (c *Config) GetAllowPlaintextKeys() bool {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.AllowPlaintextKeys
+	}
+	return false
+}
+
+func (c *Config) GetPkcs11() *keybackend.Pkcs11Config {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.Pkcs11
+	}
+	return nil
+}
+
+func (c *Config) GetSqnManagement() *SqnManagement {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.SqnManagement
+	}
+	return nil
+}
+
+func (c *Config) GetAuthVectorPool() *AuthVectorPool {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.AuthVectorPool
+	}
+	return nil
+}
+
+func (c *Config) GetAdmin() *Admin {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.Admin
+	}
+	return nil
+}
+
+func (c *Config) GetAuthLockout() *AuthLockout {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.AuthLockout
+	}
+	return nil
+}
+
+func (c *Config) GetSuciCache() *SuciCache {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration != nil {
+		return c.Configuration.SuciCache
+	}
+	return nil
+}
+
 func (c *Config) AreMetricsEnabled() bool {
 	c.RLock()
 	defer c.RUnlock()
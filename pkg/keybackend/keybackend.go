@@ -0,0 +1,98 @@
+// Package keybackend lets the UDM use private keys and key encryption keys
+// that are held outside of its own memory, e.g. in an HSM. The private key
+// never leaves the backend, only the result of the operation is returned.
+package keybackend
+
+import (
+	"crypto/ecdh"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Backend names as referenced from the configuration
+const (
+	Pkcs11 = "pkcs11"
+)
+
+// Key unwrap mechanisms supported by Backend.Unwrap
+const (
+	UnwrapAes256Gcm = "AES-256-GCM"
+	UnwrapAesKw     = "AES-KW"
+)
+
+// Backend performs operations with keys identified by a label.
+type Backend interface {
+	// ECDH computes the shared secret of the private key keyLabel on curve
+	// with peerPublicKey. peerPublicKey is the raw X25519 key or the
+	// uncompressed P-256 point.
+	ECDH(keyLabel string, curve ecdh.Curve, peerPublicKey []byte) ([]byte, error)
+	// Unwrap decrypts wrappedKey with the key encryption key keyLabel.
+	// AES-256-GCM input is nonce (96 bits) || ciphertext || tag (128 bits)
+	// authenticated with aad, AES-KW ignores aad.
+	Unwrap(keyLabel, mechanism string, wrappedKey, aad []byte) ([]byte, error)
+	Close() error
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{}
+)
+
+// Register registers b under name, closing any backend registered before.
+func Register(name string, b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	name = strings.ToLower(name)
+	if old, ok := backends[name]; ok && old != b {
+		_ = old.Close()
+	}
+	backends[name] = b
+}
+
+func Get(name string) (Backend, error) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	b, ok := backends[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("key backend [%s] is not configured", name)
+	}
+	return b, nil
+}
+
+// CloseAll closes and unregisters all backends.
+func CloseAll() error {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	var firstErr error
+	for name, b := range backends {
+		if err := b.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close key backend [%s]: %w", name, err)
+		}
+		delete(backends, name)
+	}
+	return firstErr
+}
+
+// DefaultPkcs11Sessions is the number of PKCS#11 sessions opened when
+// Pkcs11Config.Sessions is not set
+const DefaultPkcs11Sessions = 4
+
+// Pkcs11Config locates the token holding the keys. The user PIN is read from
+// the environment variable PinEnv when set, otherwise Pin is used. Sessions
+// bounds the operations running on the token concurrently, each one uses a
+// session of its own.
+type Pkcs11Config struct {
+	Library    string `yaml:"library" valid:"required"`
+	TokenLabel string `yaml:"tokenLabel" valid:"required"`
+	Pin        string `yaml:"pin,omitempty" valid:"optional"`
+	PinEnv     string `yaml:"pinEnv,omitempty" valid:"optional"`
+	Sessions   int    `yaml:"sessions,omitempty" valid:"optional,range(1|256)"`
+}
+
+func (c *Pkcs11Config) GetSessions() int {
+	if c.Sessions == 0 {
+		return DefaultPkcs11Sessions
+	}
+	return c.Sessions
+}
@@ -0,0 +1,248 @@
+//go:build pkcs11
+
+package keybackend
+
+import (
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+const (
+	gcmNonceSize = 12
+	gcmTagBits   = 128
+)
+
+var errPkcs11Closed = errors.New("PKCS#11 backend is closed")
+
+type pkcs11Backend struct {
+	// mu is held for reading while an operation runs and for writing to
+	// close the backend
+	mu  sync.RWMutex
+	ctx *pkcs11.Ctx
+	// A PKCS#11 session must not be used concurrently, idle sessions wait
+	// here for the next operation
+	sessions chan pkcs11.SessionHandle
+	opened   []pkcs11.SessionHandle
+}
+
+// NewPkcs11Backend loads the PKCS#11 module of cfg and logs in to the token
+// with the configured label.
+func NewPkcs11Backend(cfg *Pkcs11Config) (Backend, error) {
+	ctx := pkcs11.New(cfg.Library)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 library [%s]", cfg.Library)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("PKCS#11 initialize failed: %w", err)
+	}
+
+	b := &pkcs11Backend{ctx: ctx}
+	if err := b.open(cfg); err != nil {
+		for _, session := range b.opened {
+			_ = ctx.CloseSession(session)
+		}
+		_ = ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *pkcs11Backend) open(cfg *Pkcs11Config) error {
+	slots, err := b.ctx.GetSlotList(true)
+	if err != nil {
+		return fmt.Errorf("PKCS#11 get slot list failed: %w", err)
+	}
+	slot, found := uint(0), false
+	for _, s := range slots {
+		info, err := b.ctx.GetTokenInfo(s)
+		if err != nil {
+			return fmt.Errorf("PKCS#11 get token info failed: %w", err)
+		}
+		if strings.TrimRight(info.Label, " \x00") == cfg.TokenLabel {
+			slot, found = s, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("PKCS#11 token [%s] not found", cfg.TokenLabel)
+	}
+
+	b.sessions = make(chan pkcs11.SessionHandle, cfg.GetSessions())
+	for i := 0; i < cfg.GetSessions(); i++ {
+		session, err := b.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		if err != nil {
+			return fmt.Errorf("PKCS#11 open session failed: %w", err)
+		}
+		b.opened = append(b.opened, session)
+		b.sessions <- session
+	}
+
+	// The login state is shared by all sessions of the application
+	pin := cfg.Pin
+	if cfg.PinEnv != "" {
+		pin = os.Getenv(cfg.PinEnv)
+	}
+	err = b.ctx.Login(b.opened[0], pkcs11.CKU_USER, pin)
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		return fmt.Errorf("PKCS#11 login failed: %w", err)
+	}
+	return nil
+}
+
+// withSession runs f on an idle session, waiting for one when all of them are
+// in use.
+func (b *pkcs11Backend) withSession(f func(session pkcs11.SessionHandle) ([]byte, error)) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.ctx == nil {
+		return nil, errPkcs11Closed
+	}
+	session := <-b.sessions
+	defer func() {
+		b.sessions <- session
+	}()
+	return f(session)
+}
+
+func (b *pkcs11Backend) findKey(session pkcs11.SessionHandle, label string, class uint) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := b.ctx.FindObjectsInit(session, template); err != nil {
+		return 0, fmt.Errorf("PKCS#11 find objects failed: %w", err)
+	}
+	objs, _, err := b.ctx.FindObjects(session, 2)
+	if finalErr := b.ctx.FindObjectsFinal(session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("PKCS#11 find objects failed: %w", err)
+	}
+	switch len(objs) {
+	case 0:
+		return 0, fmt.Errorf("PKCS#11 key [%s] not found", label)
+	case 1:
+		return objs[0], nil
+	default:
+		return 0, fmt.Errorf("PKCS#11 key label [%s] is not unique", label)
+	}
+}
+
+// extractSessionKey reads and destroys a session key derived or unwrapped
+// inside the token.
+func (b *pkcs11Backend) extractSessionKey(session pkcs11.SessionHandle, key pkcs11.ObjectHandle) ([]byte, error) {
+	defer func() {
+		_ = b.ctx.DestroyObject(session, key)
+	}()
+	attrs, err := b.ctx.GetAttributeValue(session, key, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PKCS#11 get key value failed: %w", err)
+	}
+	return attrs[0].Value, nil
+}
+
+func sessionKeyTemplate(valueLen int) []*pkcs11.Attribute {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+	}
+	if valueLen > 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, valueLen))
+	}
+	return template
+}
+
+func (b *pkcs11Backend) ECDH(keyLabel string, curve ecdh.Curve, peerPublicKey []byte) ([]byte, error) {
+	if _, err := curve.NewPublicKey(peerPublicKey); err != nil {
+		return nil, fmt.Errorf("invalid peer public key: %w", err)
+	}
+
+	return b.withSession(func(session pkcs11.SessionHandle) ([]byte, error) {
+		priv, err := b.findKey(session, keyLabel, pkcs11.CKO_PRIVATE_KEY)
+		if err != nil {
+			return nil, err
+		}
+		// Both X25519 and P-256 give a 256 bits shared secret
+		mech := []*pkcs11.Mechanism{
+			pkcs11.NewMechanism(pkcs11.CKM_ECDH1_DERIVE,
+				pkcs11.NewECDH1DeriveParams(pkcs11.CKD_NULL, nil, peerPublicKey)),
+		}
+		secret, err := b.ctx.DeriveKey(session, mech, priv, sessionKeyTemplate(32))
+		if err != nil {
+			return nil, fmt.Errorf("PKCS#11 ECDH derive failed: %w", err)
+		}
+		return b.extractSessionKey(session, secret)
+	})
+}
+
+func (b *pkcs11Backend) Unwrap(keyLabel, mechanism string, wrappedKey, aad []byte) ([]byte, error) {
+	return b.withSession(func(session pkcs11.SessionHandle) ([]byte, error) {
+		kek, err := b.findKey(session, keyLabel, pkcs11.CKO_SECRET_KEY)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToUpper(mechanism) {
+		case UnwrapAesKw:
+			mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP, nil)}
+			key, err := b.ctx.UnwrapKey(session, mech, kek, wrappedKey, sessionKeyTemplate(0))
+			if err != nil {
+				return nil, fmt.Errorf("PKCS#11 AES-KW unwrap failed: %w", err)
+			}
+			return b.extractSessionKey(session, key)
+		case UnwrapAes256Gcm:
+			if len(wrappedKey) < gcmNonceSize+gcmTagBits/8 {
+				return nil, fmt.Errorf("AES-GCM ciphertext too short")
+			}
+			params := pkcs11.NewGCMParams(wrappedKey[:gcmNonceSize], aad, gcmTagBits)
+			defer params.Free()
+			mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}
+			if err := b.ctx.DecryptInit(session, mech, kek); err != nil {
+				return nil, fmt.Errorf("PKCS#11 AES-GCM decrypt failed: %w", err)
+			}
+			plaintext, err := b.ctx.Decrypt(session, wrappedKey[gcmNonceSize:])
+			if err != nil {
+				return nil, fmt.Errorf("PKCS#11 AES-GCM decrypt failed: %w", err)
+			}
+			return plaintext, nil
+		default:
+			return nil, fmt.Errorf("unsupported unwrap mechanism [%s]", mechanism)
+		}
+	})
+}
+
+// Close waits for the running operations and closes all sessions.
+func (b *pkcs11Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx == nil {
+		return nil
+	}
+	_ = b.ctx.Logout(b.opened[0])
+	var err error
+	for _, session := range b.opened {
+		if closeErr := b.ctx.CloseSession(session); err == nil {
+			err = closeErr
+		}
+	}
+	if finalizeErr := b.ctx.Finalize(); err == nil {
+		err = finalizeErr
+	}
+	b.ctx.Destroy()
+	b.ctx = nil
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !pkcs11
+
+package keybackend
+
+import "fmt"
+
+// NewPkcs11Backend needs cgo and the pkcs11 build tag.
+func NewPkcs11Backend(cfg *Pkcs11Config) (Backend, error) {
+	return nil, fmt.Errorf("UDM is built without PKCS#11 support, rebuild with -tags pkcs11")
+}
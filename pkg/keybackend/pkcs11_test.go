@@ -0,0 +1,137 @@
+//go:build pkcs11
+
+package keybackend
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+// DER encoded OID of prime256v1
+var p256EcParams = []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+
+// newTestBackend opens an initialized token, e.g. of SoftHSM:
+//
+//	softhsm2-util --init-token --free --label udm --pin 1234 --so-pin 1234
+//	PKCS11_LIBRARY=/usr/lib/softhsm/libsofthsm2.so PKCS11_TOKEN_LABEL=udm PKCS11_PIN=1234 \
+//		go test -tags pkcs11 ./pkg/keybackend/
+func newTestBackend(t *testing.T) *pkcs11Backend {
+	library := os.Getenv("PKCS11_LIBRARY")
+	if library == "" {
+		t.Skip("PKCS11_LIBRARY is not set")
+	}
+	b, err := NewPkcs11Backend(&Pkcs11Config{
+		Library:    library,
+		TokenLabel: os.Getenv("PKCS11_TOKEN_LABEL"),
+		PinEnv:     "PKCS11_PIN",
+		Sessions:   2,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+	return b.(*pkcs11Backend)
+}
+
+func createSessionObject(t *testing.T, b *pkcs11Backend, template []*pkcs11.Attribute) {
+	template = append(template, pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false))
+	_, err := b.withSession(func(session pkcs11.SessionHandle) ([]byte, error) {
+		_, err := b.ctx.CreateObject(session, template)
+		return nil, err
+	})
+	require.NoError(t, err)
+}
+
+func TestPkcs11ECDH(t *testing.T) {
+	b := newTestBackend(t)
+
+	privBytes, err := hex.DecodeString("f1ab1074477ebcc7f554ea1c5fc368b1616730155e0041ac447d6301975fecda")
+	require.NoError(t, err)
+	createSessionObject(t, b, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, "hn-key-b"),
+		pkcs11.NewAttribute(pkcs11.CKA_DERIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, p256EcParams),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, privBytes),
+	})
+
+	hnPriv, err := ecdh.P256().NewPrivateKey(privBytes)
+	require.NoError(t, err)
+	uePriv, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	expected, err := uePriv.ECDH(hnPriv.PublicKey())
+	require.NoError(t, err)
+
+	sharedKey, err := b.ECDH("hn-key-b", ecdh.P256(), uePriv.PublicKey().Bytes())
+	require.NoError(t, err)
+	require.Equal(t, expected, sharedKey)
+
+	_, err = b.ECDH("unknown", ecdh.P256(), uePriv.PublicKey().Bytes())
+	require.Error(t, err)
+}
+
+func TestPkcs11Unwrap(t *testing.T) {
+	b := newTestBackend(t)
+
+	kek, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	require.NoError(t, err)
+	createSessionObject(t, b, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, "kek"),
+		pkcs11.NewAttribute(pkcs11.CKA_UNWRAP, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, kek),
+	})
+
+	// RFC 3394 clause 4.6
+	wrapped, err := hex.DecodeString("64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7")
+	require.NoError(t, err)
+	plaintext, err := b.Unwrap("kek", UnwrapAesKw, wrapped, nil)
+	require.NoError(t, err)
+	require.Equal(t, "00112233445566778899aabbccddeeff", hex.EncodeToString(plaintext))
+
+	block, err := aes.NewCipher(kek)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+	subscriberKey, err := hex.DecodeString("8baf473f2f8fd09487cccbd7097c6862")
+	require.NoError(t, err)
+	aad := []byte("imsi-208930000000001|encPermanentKey")
+	plaintext, err = b.Unwrap("kek", UnwrapAes256Gcm, aead.Seal(nonce, nonce, subscriberKey, aad), aad)
+	require.NoError(t, err)
+	require.Equal(t, subscriberKey, plaintext)
+	_, err = b.Unwrap("kek", UnwrapAes256Gcm, aead.Seal(nonce, nonce, subscriberKey, aad), nil)
+	require.Error(t, err)
+
+	// more operations than sessions wait for an idle one
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := b.Unwrap("kek", UnwrapAesKw, wrapped, nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, b.sessions, 2)
+}
@@ -14,11 +14,14 @@ import (
 	"github.com/free5gc/openapi/nrf/NFManagement"
 	udm_context "github.com/free5gc/udm/internal/context"
 	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/internal/metrics/ueau"
 	"github.com/free5gc/udm/internal/sbi"
 	"github.com/free5gc/udm/internal/sbi/consumer"
 	"github.com/free5gc/udm/internal/sbi/processor"
+	"github.com/free5gc/udm/internal/util"
 	"github.com/free5gc/udm/pkg/app"
 	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/keybackend"
 	"github.com/free5gc/util/metrics"
 	"github.com/free5gc/util/metrics/utils"
 )
@@ -48,6 +51,21 @@ func NewApp(ctx context.Context, cfg *factory.Config, tlsKeyLogPath string) (*Ud
 	udm.SetLogLevel(cfg.GetLogLevel())
 	udm.SetReportCaller(cfg.GetLogReportCaller())
 	udm_context.Init()
+	if pkcs11Cfg := cfg.GetPkcs11(); pkcs11Cfg != nil {
+		backend, err := keybackend.NewPkcs11Backend(pkcs11Cfg)
+		if err != nil {
+			return udm, err
+		}
+		keybackend.Register(keybackend.Pkcs11, backend)
+	}
+	util.RegisterAuthAlgorithm(util.AuthAlgorithmTuak, util.NewTuakAlgorithm(cfg.GetTuak()))
+	if err := util.InitKeyEncryptionKeys(cfg.GetKeyEncryptionKeys()); err != nil {
+		return udm, err
+	}
+	util.SetAllowPlaintextKeys(cfg.GetAllowPlaintextKeys())
+	if err := util.InitSqnManagement(cfg.GetSqnManagement()); err != nil {
+		return udm, err
+	}
 
 	consumer, err := consumer.NewConsumer(udm)
 	if err != nil {
@@ -70,6 +88,7 @@ func NewApp(ctx context.Context, cfg *factory.Config, tlsKeyLogPath string) (*Ud
 
 	features := map[utils.MetricTypeEnabled]bool{utils.SBI: true}
 	customMetrics := make(map[utils.MetricTypeEnabled][]prometheus.Collector)
+	customMetrics[utils.SBI] = append(customMetrics[utils.SBI], ueau.GetUeauMetrics(cfg.GetMetricsNamespace())...)
 	if cfg.AreMetricsEnabled() {
 		if udm.metricsServer, err = metrics.NewServer(
 			getInitMetrics(cfg, features, customMetrics), tlsKeyLogPath, logger.InitLog); err != nil {
@@ -209,6 +228,10 @@ func (a *UdmApp) terminateProcedure() {
 		logger.InitLog.Infof("Deregister from NRF successfully")
 	}
 
+	if err := keybackend.CloseAll(); err != nil {
+		logger.MainLog.Errorf("Close key backends failed: %+v", err)
+	}
+
 	logger.MainLog.Infof("UDM SBI Server terminated")
 }
 
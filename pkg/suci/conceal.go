@@ -0,0 +1,197 @@
+package suci
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The UE side of the protection schemes, for tooling and tests. The UDM only
+// de-conceals.
+
+var (
+	imsiRegex = regexp.MustCompile(`^imsi-(\d{5,15})$`)
+	naiRegex  = regexp.MustCompile(`^nai-([^@\s]+)@(\S+)$`)
+)
+
+// GenerateKey returns a new home network key pair of scheme, hex encoded as
+// in SuciProfile. The scheme id is case-insensitive.
+func GenerateKey(scheme string) (privateKey, publicKey string, err error) {
+	s, ok := LookupScheme(strings.ToUpper(scheme))
+	if !ok || s.GenerateKey == nil {
+		return "", "", fmt.Errorf("%w: %s", ErrorUnsupportedScheme, scheme)
+	}
+	priv, pub, err := s.GenerateKey()
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(priv), hex.EncodeToString(pub), nil
+}
+
+// Conceal returns the SUCI of supi protected by scheme with the hex encoded
+// home network public key keyID. mncLen is the number of digits of the MNC
+// of an IMSI. The null scheme takes neither key id nor public key.
+func Conceal(supi string, mncLen int, routingIndicator, scheme string, keyID int, publicKey string) (string, error) {
+	var supiType, homeNetworkId, clearText string
+	if m := imsiRegex.FindStringSubmatch(supi); m != nil {
+		if mncLen != 2 && mncLen != 3 {
+			return "", fmt.Errorf("invalid MNC length %d", mncLen)
+		}
+		imsi := m[1]
+		if len(imsi) <= 3+mncLen {
+			return "", fmt.Errorf("invalid IMSI [%s]", imsi)
+		}
+		supiType = SupiTypeIMSI
+		homeNetworkId = imsi[:3] + "-" + imsi[3:3+mncLen]
+		clearText = imsi[3+mncLen:]
+	} else if m := naiRegex.FindStringSubmatch(supi); m != nil {
+		supiType = SupiTypeNAI
+		homeNetworkId = m[2]
+		clearText = m[1]
+	} else {
+		return "", fmt.Errorf("unknown supi [%s]", supi)
+	}
+
+	scheme = strings.ToUpper(scheme)
+	schemeOutput := clearText
+	if scheme == NullScheme {
+		keyID = 0
+	} else {
+		s, ok := LookupScheme(scheme)
+		if !ok || s.Conceal == nil {
+			return "", fmt.Errorf("%w: %s", ErrorUnsupportedScheme, scheme)
+		}
+		if keyID < 1 || keyID > 255 {
+			return "", fmt.Errorf("invalid home network public key id %d", keyID)
+		}
+		hnPubKey, err := hex.DecodeString(publicKey)
+		if err != nil {
+			return "", fmt.Errorf("hex DecodeString error: %w", err)
+		}
+		output, err := s.Conceal(schemeInput(clearText, supiType), hnPubKey)
+		if err != nil {
+			return "", err
+		}
+		schemeOutput = hex.EncodeToString(output)
+	}
+
+	suci := strings.Join([]string{
+		PrefixSUCI, supiType, homeNetworkId, routingIndicator, scheme, strconv.Itoa(keyID), schemeOutput,
+	}, "-")
+	if parseSuci(suci) == nil {
+		return "", fmt.Errorf("invalid suci [%s]", suci)
+	}
+	return suci, nil
+}
+
+// schemeInput is the inverse of calcSchemeResult
+func schemeInput(clearText, supiType string) []byte {
+	if supiType != SupiTypeIMSI {
+		return []byte(clearText)
+	}
+	if len(clearText)%2 == 1 {
+		clearText += "f"
+	}
+	msin, err := hex.DecodeString(clearText)
+	if err != nil {
+		return nil
+	}
+	return swapNibbles(msin)
+}
+
+func encryptWithKdf(sharedKey, kdfPubKey, plainText []byte,
+	encKeyLen, macKeyLen, hashLen, icbLen, macLen int,
+) ([]byte, error) {
+	kdfKey := AnsiX963KDF(sharedKey, kdfPubKey, encKeyLen, macKeyLen, hashLen)
+	encKey := kdfKey[:encKeyLen]
+	icb := kdfKey[encKeyLen : encKeyLen+icbLen]
+	macKey := kdfKey[len(kdfKey)-macKeyLen:]
+
+	cipherText, err := Aes128ctr(plainText, encKey, icb)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := HmacSha256(cipherText, macKey, macLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(cipherText, mac...), nil
+}
+
+func generateKeyA() (privateKey, publicKey []byte, err error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv.Bytes(), priv.PublicKey().Bytes(), nil
+}
+
+func concealA(plainText, hnPubKey []byte) ([]byte, error) {
+	hnPub, err := ecdh.X25519().NewPublicKey(hnPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse X25519 public key: %w", err)
+	}
+	ephPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedKey, err := ephPriv.ECDH(hnPub)
+	if err != nil {
+		return nil, err
+	}
+	ephPubKey := ephPriv.PublicKey().Bytes()
+	output, err := encryptWithKdf(sharedKey, ephPubKey, plainText,
+		ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen, ProfileAIcbLen, ProfileAMacLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephPubKey, output...), nil
+}
+
+// generateKeyB returns the public key compressed
+func generateKeyB() (privateKey, publicKey []byte, err error) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv.Bytes(), compressP256(priv.PublicKey().Bytes()), nil
+}
+
+func concealB(plainText, hnPubKey []byte) ([]byte, error) {
+	if len(hnPubKey) == 33 {
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), hnPubKey)
+		if x == nil || y == nil {
+			return nil, fmt.Errorf("failed to uncompress public key")
+		}
+		hnPubKey = elliptic.Marshal(elliptic.P256(), x, y)
+	}
+	hnPub, err := ecdh.P256().NewPublicKey(hnPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create P-256 public key: %w", err)
+	}
+	ephPriv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedKey, err := ephPriv.ECDH(hnPub)
+	if err != nil {
+		return nil, err
+	}
+	ephPubKey := compressP256(ephPriv.PublicKey().Bytes())
+	output, err := encryptWithKdf(sharedKey, ephPubKey, plainText,
+		ProfileBEncKeyLen, ProfileBMacKeyLen, ProfileBHashLen, ProfileBIcbLen, ProfileBMacLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephPubKey, output...), nil
+}
+
+func compressP256(uncompressed []byte) []byte {
+	x, y := elliptic.Unmarshal(elliptic.P256(), uncompressed)
+	return elliptic.MarshalCompressed(elliptic.P256(), x, y)
+}
@@ -0,0 +1,67 @@
+package suci
+
+import "testing"
+
+func TestConceal(t *testing.T) {
+	suciProfiles := make([]SuciProfile, 0)
+	for _, scheme := range []string{ProfileAScheme, ProfileBScheme} {
+		privateKey, publicKey, err := GenerateKey(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suciProfiles = append(suciProfiles, SuciProfile{
+			ProtectionScheme: scheme,
+			PrivateKey:       privateKey,
+			PublicKey:        publicKey,
+		})
+	}
+
+	testCases := []struct {
+		supi      string
+		mncLen    int
+		scheme    string
+		keyID     int
+		expectErr bool
+	}{
+		{supi: "imsi-2089300007487", mncLen: 2, scheme: NullScheme},
+		{supi: "imsi-208930000000001", mncLen: 2, scheme: ProfileAScheme, keyID: 1},
+		{supi: "imsi-20893000000001", mncLen: 3, scheme: ProfileAScheme, keyID: 1},
+		{supi: "imsi-208930000000001", mncLen: 2, scheme: ProfileBScheme, keyID: 2},
+		{supi: "nai-bob@example.com", scheme: ProfileBScheme, keyID: 2},
+		{supi: "nai-bob@example.com", scheme: NullScheme},
+		{supi: "imsi-208930000000001", mncLen: 4, scheme: ProfileAScheme, keyID: 1, expectErr: true},
+		{supi: "imsi-208930000000001", mncLen: 2, scheme: "D", keyID: 1, expectErr: true},
+		{supi: "imsi-208930000000001", mncLen: 2, scheme: ProfileAScheme, keyID: 0, expectErr: true},
+		{supi: "gci-0000", scheme: NullScheme, expectErr: true},
+	}
+	for i, tc := range testCases {
+		publicKey := ""
+		if tc.keyID > 0 {
+			publicKey = suciProfiles[(tc.keyID-1)%len(suciProfiles)].PublicKey
+		}
+		suci, err := Conceal(tc.supi, tc.mncLen, "0", tc.scheme, tc.keyID, publicKey)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("TC%d fail: expected error, got suci[%s]\n", i, suci)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("TC%d fail: err[%v]\n", i, err)
+			continue
+		}
+		if supi, err := ToSupi(suci, suciProfiles); err != nil {
+			t.Errorf("TC%d fail: suci[%s] err[%v]\n", i, suci, err)
+		} else if supi != tc.supi {
+			t.Errorf("TC%d fail: supi[%s], expected[%s]\n", i, supi, tc.supi)
+		}
+	}
+}
+
+func TestGenerateKeySchemeCase(t *testing.T) {
+	if _, _, err := GenerateKey("c"); err != nil {
+		t.Errorf("lower case scheme id fail: err[%v]\n", err)
+	}
+	if _, _, err := GenerateKey("D"); err == nil {
+		t.Errorf("unknown scheme id fail: expected error\n")
+	}
+}
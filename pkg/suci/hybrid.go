@@ -0,0 +1,122 @@
+package suci
+
+import (
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/free5gc/udm/internal/logger"
+)
+
+// Hybrid X25519+ML-KEM-768 profile, in the operator specific range of the
+// protection schemes. It extends profile A with an ML-KEM-768 encapsulation
+// so that the SUPI stays concealed should X25519 be broken.
+//
+// The scheme output is the ephemeral X25519 public key || the ML-KEM-768
+// ciphertext || the cipher text || the MAC tag. The X25519 and the ML-KEM
+// shared secrets, concatenated, are input to the ANSI X9.63 KDF with the
+// ephemeral public key || the ML-KEM ciphertext as shared info. Encryption
+// and MAC are those of profile A.
+//
+// The home network private key is the X25519 private key || the ML-KEM-768
+// seed, the public key is the X25519 public key || the ML-KEM-768
+// encapsulation key.
+const (
+	ProfileHybridScheme     = "C"
+	ProfileHybridPrivKeyLen = 32 + mlkem.SeedSize                // octets
+	ProfileHybridPubKeyLen  = 32 + mlkem.EncapsulationKeySize768 // octets
+)
+
+func profileHybrid(input, supiType string, profile SuciProfile) (string, error) {
+	logger.SuciLog.Infoln("SuciToSupi Profile Hybrid")
+
+	s, err := hex.DecodeString(input)
+	if err != nil {
+		return "", fmt.Errorf("hex DecodeString error: %w", err)
+	}
+
+	const ProfileHybridEphPubKeyLen = 32
+	const ProfileHybridKemLen = ProfileHybridEphPubKeyLen + mlkem.CiphertextSize768
+	if len(s) < ProfileHybridKemLen+ProfileAMacLen {
+		return "", fmt.Errorf("suci input too short")
+	}
+
+	peerPubKey := s[:ProfileHybridEphPubKeyLen]
+	kemCipherText := s[ProfileHybridEphPubKeyLen:ProfileHybridKemLen]
+	cipherText := s[ProfileHybridKemLen : len(s)-ProfileAMacLen]
+	providedMac := s[len(s)-ProfileAMacLen:]
+
+	privKey, err := hex.DecodeString(profile.PrivateKey)
+	if err != nil || len(privKey) != ProfileHybridPrivKeyLen {
+		return "", fmt.Errorf("invalid hybrid private key")
+	}
+	x25519Profile := profile
+	x25519Profile.PrivateKey = hex.EncodeToString(privKey[:32])
+	ecdhSharedKey, err := ecdhX25519(x25519Profile, peerPubKey)
+	if err != nil {
+		return "", err
+	}
+
+	decapsulationKey, err := mlkem.NewDecapsulationKey768(privKey[32:])
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ML-KEM-768 private key: %w", err)
+	}
+	kemSharedKey, err := decapsulationKey.Decapsulate(kemCipherText)
+	if err != nil {
+		return "", fmt.Errorf("ML-KEM-768 decapsulation error: %w", err)
+	}
+
+	plainText, err := decryptWithKdf(append(ecdhSharedKey, kemSharedKey...), s[:ProfileHybridKemLen],
+		cipherText, providedMac, ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen, ProfileAIcbLen, ProfileAMacLen)
+	if err != nil {
+		return "", err
+	}
+	return calcSchemeResult(plainText, supiType), nil
+}
+
+func generateKeyHybrid() (privateKey, publicKey []byte, err error) {
+	x25519PrivKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	decapsulationKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey = append(x25519PrivKey.Bytes(), decapsulationKey.Bytes()...)
+	publicKey = append(x25519PrivKey.PublicKey().Bytes(), decapsulationKey.EncapsulationKey().Bytes()...)
+	return privateKey, publicKey, nil
+}
+
+func concealHybrid(plainText, hnPubKey []byte) ([]byte, error) {
+	if len(hnPubKey) != ProfileHybridPubKeyLen {
+		return nil, fmt.Errorf("invalid hybrid public key")
+	}
+	hnX25519PubKey, err := ecdh.X25519().NewPublicKey(hnPubKey[:32])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse X25519 public key: %w", err)
+	}
+	encapsulationKey, err := mlkem.NewEncapsulationKey768(hnPubKey[32:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ML-KEM-768 public key: %w", err)
+	}
+	ephPrivKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSharedKey, err := ephPrivKey.ECDH(hnX25519PubKey)
+	if err != nil {
+		return nil, err
+	}
+	kemSharedKey, kemCipherText := encapsulationKey.Encapsulate()
+
+	sharedInfo := append(ephPrivKey.PublicKey().Bytes(), kemCipherText...)
+	output, err := encryptWithKdf(append(ecdhSharedKey, kemSharedKey...), sharedInfo, plainText,
+		ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen, ProfileAIcbLen, ProfileAMacLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(sharedInfo, output...), nil
+}
@@ -0,0 +1,113 @@
+package suci
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToSupiHybrid(t *testing.T) {
+	hnPrivKey, hnPubKey, err := GenerateKey(ProfileHybridScheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hnPubKey) != 2*ProfileHybridPubKeyLen {
+		t.Fatalf("public key length %d, expected %d", len(hnPubKey)/2, ProfileHybridPubKeyLen)
+	}
+	suciProfiles := []SuciProfile{
+		{
+			KeyId:            3,
+			ProtectionScheme: ProfileHybridScheme,
+			PrivateKey:       hnPrivKey,
+			PublicKey:        hnPubKey,
+		},
+	}
+
+	imsiSuci, err := Conceal("imsi-208930010020860", 2, "0", ProfileHybridScheme, 3, hnPubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	naiSuci, err := Conceal("nai-alice@example.com", 0, "0", ProfileHybridScheme, 3, hnPubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imsiOutput := imsiSuci[strings.LastIndex(imsiSuci, "-")+1:]
+
+	tampered, err := hex.DecodeString(imsiOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered[len(tampered)-1] ^= 0x01
+	tamperedOutput := hex.EncodeToString(tampered)
+
+	testCases := []struct {
+		suci         string
+		expectedSupi string
+		expectedErr  error
+	}{
+		{
+			suci:         imsiSuci,
+			expectedSupi: "imsi-208930010020860",
+		},
+		{
+			suci:         "suci-0-208-93-0-c-3-" + imsiOutput,
+			expectedSupi: "imsi-208930010020860",
+		},
+		{
+			suci:         naiSuci,
+			expectedSupi: "nai-alice@example.com",
+		},
+		{
+			suci:        "suci-0-208-93-0-C-3-" + tamperedOutput,
+			expectedErr: ErrorMacFailure,
+		},
+		{
+			suci:        "suci-0-208-93-0-1-3-" + imsiOutput,
+			expectedErr: ErrorUnknownPublicKey,
+		},
+		{
+			suci:        "suci-0-208-93-0-D-3-" + imsiOutput,
+			expectedErr: ErrorUnsupportedScheme,
+		},
+	}
+	for i, tc := range testCases {
+		supi, err := ToSupi(tc.suci, suciProfiles)
+		if tc.expectedErr != nil {
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("TC%d fail: err[%v], expected[%v]\n", i, err, tc.expectedErr)
+			}
+		} else if err != nil {
+			t.Errorf("TC%d fail: err[%v]\n", i, err)
+		} else if supi != tc.expectedSupi {
+			t.Errorf("TC%d fail: supi[%s], expected[%s]\n", i, supi, tc.expectedSupi)
+		}
+	}
+}
+
+func TestRegisterScheme(t *testing.T) {
+	deconceal := func(schemeOutput, supiType string, profile SuciProfile) (string, error) {
+		return schemeOutput, nil
+	}
+	for _, id := range []string{"0", "G", "d", "10", ""} {
+		if err := RegisterScheme(id, Scheme{Deconceal: deconceal}); err == nil {
+			t.Errorf("scheme id [%s] registered, expected error\n", id)
+		}
+	}
+	if err := RegisterScheme("F", Scheme{}); err == nil {
+		t.Errorf("scheme without Deconceal registered, expected error\n")
+	}
+
+	if err := RegisterScheme("F", Scheme{Name: "Test", Deconceal: deconceal}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		schemesMu.Lock()
+		delete(schemes, "F")
+		schemesMu.Unlock()
+	}()
+	suciProfiles := []SuciProfile{{KeyId: 1, ProtectionScheme: "F"}}
+	if supi, err := ToSupi("suci-0-208-93-0-F-1-0123", suciProfiles); err != nil || supi != "imsi-208930123" {
+		t.Errorf("supi[%s] err[%v], expected[imsi-208930123]\n", supi, err)
+	}
+}
@@ -0,0 +1,82 @@
+package suci
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Scheme is a protection scheme of the SUPI, TS 33.501 Annex C.3. Scheme
+// identifiers 1-2 are the ECIES profiles A and B, 12-15 (C-F) are operator
+// specific.
+type Scheme struct {
+	Name string
+	// Deconceal returns the SUPI part concealed in the hex encoded scheme
+	// output: the MSIN digits for SUPI type IMSI, the username for NAI.
+	Deconceal func(schemeOutput, supiType string, profile SuciProfile) (string, error)
+	// Conceal and GenerateKey are the UE side, for tooling. Conceal returns
+	// the scheme output of plainText, GenerateKey a home network key pair.
+	// Both are optional.
+	Conceal     func(plainText, hnPublicKey []byte) ([]byte, error)
+	GenerateKey func() (privateKey, publicKey []byte, err error)
+	// Lengths in octets of the home network keys
+	PrivateKeyLen int
+	PublicKeyLens []int
+	// Whether the private key can be held by a key backend
+	KeyBackend bool
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]Scheme{
+		ProfileAScheme: {
+			Name:          "Profile A",
+			Deconceal:     profileA,
+			Conceal:       concealA,
+			GenerateKey:   generateKeyA,
+			PrivateKeyLen: 32,
+			PublicKeyLens: []int{32},
+			KeyBackend:    true,
+		},
+		ProfileBScheme: {
+			Name:          "Profile B",
+			Deconceal:     profileB,
+			Conceal:       concealB,
+			GenerateKey:   generateKeyB,
+			PrivateKeyLen: 32,
+			PublicKeyLens: []int{33, 65}, // compressed, uncompressed
+			KeyBackend:    true,
+		},
+		ProfileHybridScheme: {
+			Name:          "Hybrid X25519+ML-KEM-768",
+			Deconceal:     profileHybrid,
+			Conceal:       concealHybrid,
+			GenerateKey:   generateKeyHybrid,
+			PrivateKeyLen: ProfileHybridPrivKeyLen,
+			PublicKeyLens: []int{ProfileHybridPubKeyLen},
+		},
+	}
+)
+
+// RegisterScheme registers scheme under the identifier id, a single upper
+// case hexadecimal digit other than the null scheme 0.
+func RegisterScheme(id string, scheme Scheme) error {
+	if len(id) != 1 || id == NullScheme || !hexRegex.MatchString(id) || id != strings.ToUpper(id) {
+		return fmt.Errorf("invalid protection scheme id [%s]", id)
+	}
+	if scheme.Deconceal == nil {
+		return fmt.Errorf("protection scheme %s has no Deconceal", id)
+	}
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[id] = scheme
+	return nil
+}
+
+// LookupScheme returns the protection scheme registered under id
+func LookupScheme(id string) (Scheme, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	scheme, ok := schemes[id]
+	return scheme, ok
+}
@@ -15,17 +15,22 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/keybackend"
 )
 
 // suci-0(SUPI type: IMSI)-mcc-mnc-routingIndicator-protectionScheme-homeNetworkPublicKeyID-schemeOutput.
-// TODO: suci-1(SUPI type: NAI)-homeNetworkID-routingIndicator-protectionScheme-homeNetworkPublicKeyID-schemeOutput.
+// suci-1(SUPI type: NAI)-homeNetworkID-routingIndicator-protectionScheme-homeNetworkPublicKeyID-schemeOutput.
 
 const (
 	PrefixIMSI     = "imsi-"
+	PrefixNAI      = "nai-"
 	PrefixSUCI     = "suci"
 	SupiTypeIMSI   = "0"
+	SupiTypeNAI    = "1"
 	NullScheme     = "0"
 	ProfileAScheme = "1"
 	ProfileBScheme = "2"
@@ -44,7 +49,8 @@ var (
 	// The Home Network Identifier consists of a string of
 	// characters with a variable length representing a domain name
 	// as specified in Section 2.2 of RFC 7542
-	naiTypeRegex = "(?P<naiType>1-.*)"
+	realmRegex   = `(?P<realm>[A-Za-z0-9](?:[A-Za-z0-9.-]*[A-Za-z0-9])?)`
+	naiTypeRegex = fmt.Sprintf("(?P<naiType>1-%s)", realmRegex)
 
 	// SUPI type; 0 = IMSI, 1 = NAI (for n3gpp)
 	supiTypeRegex = fmt.Sprintf("(?P<supi_type>%s|%s)",
@@ -53,12 +59,15 @@ var (
 
 	// Routing Indicator, used by the AUSF to find the appropriate UDM when SUCI is encrypted 1-4 digits
 	routingIndicatorRegex = `(?P<routing_indicator>\d{1,4})`
-	// Protection Scheme ID; 0 = NULL Scheme (unencrypted), 1 = Profile A, 2 = Profile B
-	protectionSchemeRegex = `(?P<protection_scheme_id>(?:[0-2]))`
+	// Protection Scheme ID; single hex digit, 0 = NULL Scheme (unencrypted), 1 = Profile A, 2 = Profile B,
+	// C-F = operator specific
+	protectionSchemeRegex = `(?P<protection_scheme_id>(?:[0-9A-Fa-f]))`
 	// Public Key ID; 1-255
 	publicKeyIDRegex = `(?P<public_key_id>(?:\d{1,2}|1\d{2}|2[0-4]\d|25[0-5]))`
-	// Scheme Output; unbounded hex string (safe from ReDoS due to bounded length of SUCI)
-	schemeOutputRegex = `(?P<scheme_output>[A-Fa-f0-9]+)`
+	// Scheme Output; unbounded hex string, or the username of a NAI under the null scheme
+	// (safe from ReDoS due to bounded length of SUCI)
+	schemeOutputRegex = `(?P<scheme_output>\S+)`
+	hexRegex          = regexp.MustCompile(`^[A-Fa-f0-9]+$`)
 	// Subscription Concealed Identifier (SUCI) Encrypted SUPI as sent by the UE to the AMF; 3GPP TS 29.503 - Annex C
 	suciRegex = regexp.MustCompile(fmt.Sprintf("^suci-%s-%s-%s-%s-%s$",
 		supiTypeRegex,
@@ -73,36 +82,109 @@ type Suci struct {
 	SupiType         string // 0 for IMSI, 1 for NAI
 	Mcc              string // 3 digits
 	Mnc              string // 2-3 digits
-	HomeNetworkId    string // variable-length string
+	HomeNetworkId    string // realm of a NAI, variable-length string
 	RoutingIndicator string // 1-4 digits
-	ProtectionScheme string // 0-2
+	ProtectionScheme string // 0-F
 	PublicKeyID      string // 1-255
 	SchemeOutput     string // hex string
 }
 
 func parseSuci(input string) *Suci {
 	matches := suciRegex.FindStringSubmatch(input)
-	if matches == nil || len(matches) != 10 {
+	if matches == nil {
 		return nil
 	}
 
-	// The indices correspond to the order of the regex groups in the pattern
-	return &Suci{
-		SupiType:         matches[1], // First capture group
-		Mcc:              matches[3], // Third capture group
-		Mnc:              matches[4], // Fourth capture group
-		HomeNetworkId:    matches[5], // Fifth capture group
-		RoutingIndicator: matches[6], // Sixth capture group
-		ProtectionScheme: matches[7], // Seventh capture group
-		PublicKeyID:      matches[8], // Eighth capture group
-		SchemeOutput:     matches[9], // Ninth capture group
+	group := func(name string) string {
+		return matches[suciRegex.SubexpIndex(name)]
 	}
+	parsedSuci := &Suci{
+		SupiType:         group("supi_type"),
+		Mcc:              group("mcc"),
+		Mnc:              group("mnc"),
+		HomeNetworkId:    group("realm"),
+		RoutingIndicator: group("routing_indicator"),
+		ProtectionScheme: strings.ToUpper(group("protection_scheme_id")),
+		PublicKeyID:      group("public_key_id"),
+		SchemeOutput:     group("scheme_output"),
+	}
+	// only the username of a NAI is not hex encoded
+	isNaiUsername := parsedSuci.HomeNetworkId != "" && parsedSuci.ProtectionScheme == NullScheme
+	if !isNaiUsername && !hexRegex.MatchString(parsedSuci.SchemeOutput) {
+		return nil
+	}
+	return parsedSuci
 }
 
 type SuciProfile struct {
+	// Home network public key identifier, 1-255. Without it the identifier is
+	// the position of the profile in the list, counting from 1.
+	KeyId            int    `yaml:"KeyId,omitempty"`
 	ProtectionScheme string `yaml:"ProtectionScheme,omitempty"`
 	PrivateKey       string `yaml:"PrivateKey,omitempty"`
 	PublicKey        string `yaml:"PublicKey,omitempty"`
+	// When KeyBackend is set, the private key is held by that key backend
+	// under KeyLabel and PrivateKey is not used.
+	KeyBackend string `yaml:"KeyBackend,omitempty"`
+	KeyLabel   string `yaml:"KeyLabel,omitempty"`
+	// Period the key de-conceals SUCIs in, as RFC 3339 times, unbounded if
+	// empty. Keys being rotated out stay listed until their NotAfter.
+	NotBefore string `yaml:"NotBefore,omitempty"`
+	NotAfter  string `yaml:"NotAfter,omitempty"`
+}
+
+// GetKeyId returns the key identifier of the profile at index i of the list
+func (p SuciProfile) GetKeyId(i int) int {
+	if p.KeyId != 0 {
+		return p.KeyId
+	}
+	return i + 1
+}
+
+// Validity returns the period the key de-conceals SUCIs in, zero times
+// meaning unbounded.
+func (p SuciProfile) Validity() (notBefore, notAfter time.Time, err error) {
+	if p.NotBefore != "" {
+		if notBefore, err = time.Parse(time.RFC3339, p.NotBefore); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid NotBefore: %w", err)
+		}
+	}
+	if p.NotAfter != "" {
+		if notAfter, err = time.Parse(time.RFC3339, p.NotAfter); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid NotAfter: %w", err)
+		}
+	}
+	return notBefore, notAfter, nil
+}
+
+// findProfile returns the profile of home network public key keyID, which
+// must be for scheme and valid at now.
+func findProfile(keyID, scheme string, suciProfiles []SuciProfile, now time.Time) (SuciProfile, error) {
+	if _, ok := LookupScheme(scheme); !ok {
+		return SuciProfile{}, fmt.Errorf("%w: %s", ErrorUnsupportedScheme, scheme)
+	}
+	id, err := strconv.Atoi(keyID)
+	if err != nil {
+		return SuciProfile{}, fmt.Errorf("parse HNPublicKeyID error: %w", err)
+	}
+	for i, profile := range suciProfiles {
+		if profile.GetKeyId(i) != id {
+			continue
+		}
+		if scheme != profile.ProtectionScheme {
+			return SuciProfile{}, fmt.Errorf("%w %d: protect Scheme mismatch [%s:%s]",
+				ErrorUnknownPublicKey, id, scheme, profile.ProtectionScheme)
+		}
+		notBefore, notAfter, err := profile.Validity()
+		if err != nil {
+			return SuciProfile{}, err
+		}
+		if (!notBefore.IsZero() && now.Before(notBefore)) || (!notAfter.IsZero() && now.After(notAfter)) {
+			return SuciProfile{}, fmt.Errorf("%w %d: not valid at %s", ErrorUnknownPublicKey, id, now.Format(time.RFC3339))
+		}
+		return profile, nil
+	}
+	return SuciProfile{}, fmt.Errorf("%w %d", ErrorUnknownPublicKey, id)
 }
 
 // profile A.
@@ -173,7 +255,8 @@ func calcSchemeResult(decryptPlainText []byte, supiType string) string {
 			result = result[:len(result)-1]
 		}
 	} else {
-		result = hex.EncodeToString(decryptPlainText)
+		// the username of a NAI, TS 33.501 clause C.2
+		result = string(decryptPlainText)
 	}
 	return result
 }
@@ -191,19 +274,35 @@ func decryptWithKdf(sharedKey, kdfPubKey, cipherText, providedMac []byte,
 		return nil, err
 	}
 	if !hmac.Equal(computedMac, providedMac) {
-		return nil, fmt.Errorf("decryption MAC failed")
+		return nil, ErrorMacFailure
 	}
 	logger.SuciLog.Infoln("decryption MAC match")
 
 	return Aes128ctr(cipherText, encKey, icb)
 }
 
-func ecdhX25519(privateKeyHex string, peerPubKey []byte) ([]byte, error) {
-	aHNPrivBytes, err := hex.DecodeString(privateKeyHex)
+func backendECDH(profile SuciProfile, curve ecdh.Curve, peerPubKey []byte) ([]byte, error) {
+	backend, err := keybackend.Get(profile.KeyBackend)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode X25519 private key: %w", err)
+		return nil, err
 	}
+	sharedKey, err := backend.ECDH(profile.KeyLabel, curve, peerPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute ECDH in key backend: %w", err)
+	}
+	return sharedKey, nil
+}
+
+func ecdhX25519(profile SuciProfile, peerPubKey []byte) ([]byte, error) {
 	x25519Curve := ecdh.X25519()
+	if profile.KeyBackend != "" {
+		return backendECDH(profile, x25519Curve, peerPubKey)
+	}
+
+	aHNPrivBytes, err := hex.DecodeString(profile.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode X25519 private key: %w", err)
+	}
 	priv, err := x25519Curve.NewPrivateKey(aHNPrivBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse X25519 private key: %w", err)
@@ -217,17 +316,14 @@ func ecdhX25519(privateKeyHex string, peerPubKey []byte) ([]byte, error) {
 
 var ErrorPublicKeyUnmarshalling = fmt.Errorf("failed to unmarshal uncompressed public key")
 
-func ecdhP256(privateKeyHex string, transmittedPubKey []byte) (sharedKey, kdfPubKey []byte, err error) {
-	bHNPrivBytes, err := hex.DecodeString(privateKeyHex)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode P-256 private key: %w", err)
-	}
-	p256Curve := ecdh.P256()
-	priv, err := p256Curve.NewPrivateKey(bHNPrivBytes)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse P-256 private key: %w", err)
-	}
+// Errors of the de-concealment of a SUCI
+var (
+	ErrorUnsupportedScheme = fmt.Errorf("protection scheme is not supported")
+	ErrorUnknownPublicKey  = fmt.Errorf("unknown home network public key")
+	ErrorMacFailure        = fmt.Errorf("decryption MAC failed")
+)
 
+func ecdhP256(profile SuciProfile, transmittedPubKey []byte) (sharedKey, kdfPubKey []byte, err error) {
 	var pubKeyForECDH []byte
 	switch transmittedPubKey[0] {
 	case 0x02, 0x03:
@@ -253,6 +349,24 @@ func ecdhP256(privateKeyHex string, transmittedPubKey []byte) (sharedKey, kdfPub
 		return nil, nil, fmt.Errorf("unknown public key format")
 	}
 
+	p256Curve := ecdh.P256()
+	if profile.KeyBackend != "" {
+		sharedKey, err = backendECDH(profile, p256Curve, pubKeyForECDH)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sharedKey, kdfPubKey, nil
+	}
+
+	bHNPrivBytes, err := hex.DecodeString(profile.PrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode P-256 private key: %w", err)
+	}
+	priv, err := p256Curve.NewPrivateKey(bHNPrivBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse P-256 private key: %w", err)
+	}
+
 	pub, err := p256Curve.NewPublicKey(pubKeyForECDH)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create P-256 public key: %w", err)
@@ -266,7 +380,7 @@ func ecdhP256(privateKeyHex string, transmittedPubKey []byte) (sharedKey, kdfPub
 	return sharedKey, kdfPubKey, nil
 }
 
-func profileA(input, supiType, privateKey string) (string, error) {
+func profileA(input, supiType string, profile SuciProfile) (string, error) {
 	logger.SuciLog.Infoln("SuciToSupi Profile A")
 
 	s, err := hex.DecodeString(input)
@@ -284,7 +398,7 @@ func profileA(input, supiType, privateKey string) (string, error) {
 	cipherText := s[ProfileAPubKeyLen : len(s)-ProfileAMacLen]
 	providedMac := s[len(s)-ProfileAMacLen:]
 
-	sharedKey, err := ecdhX25519(privateKey, peerPubKey)
+	sharedKey, err := ecdhX25519(profile, peerPubKey)
 	if err != nil {
 		return "", err
 	}
@@ -297,7 +411,7 @@ func profileA(input, supiType, privateKey string) (string, error) {
 	return calcSchemeResult(plainText, supiType), nil
 }
 
-func profileB(input, supiType, privateKey string) (string, error) {
+func profileB(input, supiType string, profile SuciProfile) (string, error) {
 	logger.SuciLog.Infoln("SuciToSupi Profile B")
 
 	s, err := hex.DecodeString(input)
@@ -323,7 +437,7 @@ func profileB(input, supiType, privateKey string) (string, error) {
 	cipherText := s[ProfileBPubKeyLen : len(s)-ProfileBMacLen]
 	providedMac := s[len(s)-ProfileBMacLen:]
 
-	sharedKey, kdfPubKey, err := ecdhP256(privateKey, transmittedPubKey)
+	sharedKey, kdfPubKey, err := ecdhP256(profile, transmittedPubKey)
 	if err != nil {
 		return "", err
 	}
@@ -336,6 +450,30 @@ func profileB(input, supiType, privateKey string) (string, error) {
 	return calcSchemeResult(plainText, supiType), nil
 }
 
+// RoutingIndicator returns the routing indicator of suci, false if suci is
+// not a SUCI.
+func RoutingIndicator(suci string) (string, bool) {
+	parsedSuci := parseSuci(suci)
+	if parsedSuci == nil {
+		return "", false
+	}
+	return parsedSuci.RoutingIndicator, true
+}
+
+// ProfileOf returns the profile of the home network public key suci is
+// concealed with, which must be valid at now. SUCIs of the null scheme have
+// none and return the zero profile.
+func ProfileOf(suci string, suciProfiles []SuciProfile, now time.Time) (SuciProfile, error) {
+	parsedSuci := parseSuci(suci)
+	if parsedSuci == nil {
+		return SuciProfile{}, fmt.Errorf("unknown suci [%s]", suci)
+	}
+	if parsedSuci.ProtectionScheme == NullScheme {
+		return SuciProfile{}, nil
+	}
+	return findProfile(parsedSuci.PublicKeyID, parsedSuci.ProtectionScheme, suciProfiles, now)
+}
+
 func ToSupi(suci string, suciProfiles []SuciProfile) (string, error) {
 	parsedSuci := parseSuci(suci)
 	if parsedSuci == nil {
@@ -347,47 +485,60 @@ func ToSupi(suci string, suciProfiles []SuciProfile) (string, error) {
 	}
 
 	logger.SuciLog.Infof("scheme %s", parsedSuci.ProtectionScheme)
-	scheme := parsedSuci.ProtectionScheme
-	mccMnc := parsedSuci.Mcc + parsedSuci.Mnc
-	supiPrefix := PrefixIMSI
-
-	if !strings.HasPrefix(parsedSuci.SupiType, SupiTypeIMSI) {
+	if strings.HasPrefix(parsedSuci.SupiType, SupiTypeNAI) {
 		logger.SuciLog.Infof("SUPI type is NAI")
-		return "", fmt.Errorf("unsupported suciType NAI")
+		return naiToSupi(parsedSuci, suciProfiles)
 	}
 	logger.SuciLog.Infof("SUPI type is IMSI")
 
+	scheme := parsedSuci.ProtectionScheme
+	mccMnc := parsedSuci.Mcc + parsedSuci.Mnc
+	supiPrefix := PrefixIMSI
+
 	if scheme == NullScheme {
 		return supiPrefix + mccMnc + parsedSuci.SchemeOutput, nil
 	}
 
-	keyIndex, err := strconv.Atoi(parsedSuci.PublicKeyID)
+	profile, err := findProfile(parsedSuci.PublicKeyID, scheme, suciProfiles, time.Now())
 	if err != nil {
-		return "", fmt.Errorf("parse HNPublicKeyID error: %w", err)
+		return "", err
 	}
-	if keyIndex < 1 || keyIndex > len(suciProfiles) {
-		return "", fmt.Errorf("keyIndex (%d) out of range (%d)", keyIndex, len(suciProfiles))
+
+	result, err := deconceal(parsedSuci.SchemeOutput, SupiTypeIMSI, profile)
+	if err != nil {
+		return "", err
 	}
+	return supiPrefix + mccMnc + result, nil
+}
 
-	profile := suciProfiles[keyIndex-1]
-	if scheme != profile.ProtectionScheme {
-		return "", fmt.Errorf("protect Scheme mismatch [%s:%s]", scheme, profile.ProtectionScheme)
+// deconceal de-conceals schemeOutput with the protection scheme of profile
+func deconceal(schemeOutput, supiType string, profile SuciProfile) (string, error) {
+	scheme, ok := LookupScheme(profile.ProtectionScheme)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrorUnsupportedScheme, profile.ProtectionScheme)
 	}
+	return scheme.Deconceal(schemeOutput, supiType, profile)
+}
 
-	switch scheme {
-	case ProfileAScheme:
-		result, err := profileA(parsedSuci.SchemeOutput, SupiTypeIMSI, profile.PrivateKey)
+// naiToSupi returns the SUPI nai-<username>@<realm> of a SUCI of SUPI type
+// NAI, TS 23.003 clause 2.2B. The realm is sent in clear, the username is
+// concealed like the MSIN of an IMSI.
+func naiToSupi(parsedSuci *Suci, suciProfiles []SuciProfile) (string, error) {
+	scheme := parsedSuci.ProtectionScheme
+	username := parsedSuci.SchemeOutput
+	if scheme != NullScheme {
+		profile, err := findProfile(parsedSuci.PublicKeyID, scheme, suciProfiles, time.Now())
 		if err != nil {
 			return "", err
 		}
-		return supiPrefix + mccMnc + result, nil
-	case ProfileBScheme:
-		result, err := profileB(parsedSuci.SchemeOutput, SupiTypeIMSI, profile.PrivateKey)
+		username, err = deconceal(parsedSuci.SchemeOutput, SupiTypeNAI, profile)
 		if err != nil {
 			return "", err
 		}
-		return supiPrefix + mccMnc + result, nil
-	default:
-		return "", fmt.Errorf("protect Scheme (%s) is not supported", scheme)
 	}
+
+	if username == "" || !utf8.ValidString(username) || strings.ContainsAny(username, "@ ") {
+		return "", fmt.Errorf("invalid NAI username [%q]", username)
+	}
+	return PrefixNAI + username + "@" + parsedSuci.HomeNetworkId, nil
 }
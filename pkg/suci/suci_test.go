@@ -1,8 +1,12 @@
 package suci
 
 import (
+	"crypto/ecdh"
+	"encoding/hex"
 	"errors"
 	"testing"
+
+	"github.com/free5gc/udm/pkg/keybackend"
 )
 
 func TestToSupi(t *testing.T) {
@@ -104,3 +108,260 @@ func TestToSupi(t *testing.T) {
 		}
 	}
 }
+
+func TestToSupiNai(t *testing.T) {
+	suciProfiles := []SuciProfile{
+		{
+			ProtectionScheme: "1", // Protect Scheme: Profile A
+			PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+			PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+		},
+		{
+			ProtectionScheme: "2", // Protect Scheme: Profile B
+			PrivateKey:       "F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA",
+			PublicKey: "0472DA71976234CE833A6907425867B82E074D44EF907DFB4B3E21C1C2256EBCD" +
+				"15A7DED52FCBB097A4ED250E036C7B9C8C7004C4EEDC4F068CD7BF8D3F900E3B4",
+		},
+	}
+	testCases := []struct {
+		name         string
+		suci         string
+		expectedSupi string
+		expectErr    bool
+	}{
+		{
+			name:         "Null scheme",
+			suci:         "suci-1-iot.example-operator.com-0-0-0-sensor-17",
+			expectedSupi: "nai-sensor-17@iot.example-operator.com",
+		},
+		{
+			name: "Profile A",
+			suci: "suci-1-example.com-12-1-1-2138438797200d73c8d2182bfcfe162ebbf6aba35ebb6123b471bcb0" +
+				"53370b482aaa09d1ccab09cb62e25ac0ad1645f8e41618c3325640",
+			expectedSupi: "nai-iot-device.0042@example.com",
+		},
+		{
+			name: "Profile B",
+			suci: "suci-1-example.com-0-2-2-0309037dcbaf00b351a1c00ca7b652f3abfed8cb3aed1c89114e2c91b8" +
+				"b14e4de95da9362781163dd61f1711621d",
+			expectedSupi: "nai-alice@example.com",
+		},
+		{
+			name: "Profile B with the key of Profile A",
+			suci: "suci-1-example.com-0-2-1-0309037dcbaf00b351a1c00ca7b652f3abfed8cb3aed1c89114e2c91b8" +
+				"b14e4de95da9362781163dd61f1711621d",
+			expectErr: true,
+		},
+		{
+			name: "Profile A with a wrong MAC",
+			suci: "suci-1-example.com-12-1-1-2138438797200d73c8d2182bfcfe162ebbf6aba35ebb6123b471bcb0" +
+				"53370b482aaa09d1ccab09cb62e25ac0ad1645f8e41618c3325641",
+			expectErr: true,
+		},
+		{
+			name:      "Username with realm",
+			suci:      "suci-1-example.com-0-0-0-alice@example.org",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid realm",
+			suci:      "suci-1-.example.com-0-0-0-alice",
+			expectErr: true,
+		},
+		{
+			name:      "IMSI with non-hex scheme output",
+			suci:      "suci-0-208-93-0-0-0-alice",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			supi, err := ToSupi(tc.suci, suciProfiles)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got supi[%s]", supi)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if supi != tc.expectedSupi {
+				t.Errorf("supi[%s], expected[%s]", supi, tc.expectedSupi)
+			}
+		})
+	}
+}
+
+func TestToSupiKeyRotation(t *testing.T) {
+	profileA := SuciProfile{
+		ProtectionScheme: "1",
+		PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+		PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+	}
+	withKey := func(keyId int, notBefore, notAfter string) SuciProfile {
+		profile := profileA
+		profile.KeyId, profile.NotBefore, profile.NotAfter = keyId, notBefore, notAfter
+		return profile
+	}
+	schemeOutput := "b2e92f836055a255837debf850b528997ce0201cb82a" +
+		"dfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+
+	testCases := []struct {
+		name         string
+		suciProfiles []SuciProfile
+		keyId        string
+		expectErr    bool
+	}{
+		{
+			name:         "Positional key id",
+			suciProfiles: []SuciProfile{profileA},
+			keyId:        "1",
+		},
+		{
+			name:         "Explicit key id",
+			suciProfiles: []SuciProfile{withKey(1, "", "2000-01-01T00:00:00Z"), withKey(9, "", "")},
+			keyId:        "9",
+		},
+		{
+			name:         "Explicit key id replaces position",
+			suciProfiles: []SuciProfile{withKey(9, "", "")},
+			keyId:        "1",
+			expectErr:    true,
+		},
+		{
+			name: "Old and new key during rotation",
+			suciProfiles: []SuciProfile{
+				withKey(1, "", "2999-01-01T00:00:00Z"),
+				withKey(2, "2000-01-01T00:00:00Z", ""),
+			},
+			keyId: "1",
+		},
+		{
+			name:         "Expired key",
+			suciProfiles: []SuciProfile{withKey(1, "", "2000-01-01T00:00:00Z")},
+			keyId:        "1",
+			expectErr:    true,
+		},
+		{
+			name:         "Key not valid yet",
+			suciProfiles: []SuciProfile{withKey(1, "2999-01-01T00:00:00Z", "")},
+			keyId:        "1",
+			expectErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			supi, err := ToSupi("suci-0-208-93-0-1-"+tc.keyId+"-"+schemeOutput, tc.suciProfiles)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got supi[%s]", supi)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if supi != "imsi-20893001002086" {
+				t.Errorf("supi[%s], expected[imsi-20893001002086]", supi)
+			}
+		})
+	}
+}
+
+// softBackend keeps the private keys in memory, standing in for an HSM
+type softBackend struct {
+	privateKeys map[string]string
+}
+
+func (b *softBackend) ECDH(keyLabel string, curve ecdh.Curve, peerPublicKey []byte) ([]byte, error) {
+	privBytes, err := hex.DecodeString(b.privateKeys[keyLabel])
+	if err != nil {
+		return nil, err
+	}
+	priv, err := curve.NewPrivateKey(privBytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := curve.NewPublicKey(peerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
+
+func (b *softBackend) Unwrap(keyLabel, mechanism string, wrappedKey, aad []byte) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (b *softBackend) Close() error {
+	return nil
+}
+
+func TestToSupiWithKeyBackend(t *testing.T) {
+	keybackend.Register("soft", &softBackend{privateKeys: map[string]string{
+		"hn-key-a": "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+		"hn-key-b": "F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA",
+	}})
+	defer func() {
+		if err := keybackend.CloseAll(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	suciProfiles := []SuciProfile{
+		{ProtectionScheme: "1", KeyBackend: "soft", KeyLabel: "hn-key-a"},
+		{ProtectionScheme: "2", KeyBackend: "soft", KeyLabel: "hn-key-b"},
+		{ProtectionScheme: "2", KeyBackend: "unknown", KeyLabel: "hn-key-b"},
+	}
+	testCases := []struct {
+		suci         string
+		expectedSupi string
+		expectErr    bool
+	}{
+		{
+			suci: "suci-0-208-93-0-1-1-b2e92f836055a255837debf850b528997ce0201cb82a" +
+				"dfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87",
+			expectedSupi: "imsi-20893001002086",
+		},
+		{
+			suci: "suci-0-208-93-0-2-2-039aab8376597021e855679a9778ea0b67396e68c66d" +
+				"f32c0f41e9acca2da9b9d146a33fc2716ac7dae96aa30a4d",
+			expectedSupi: "imsi-20893001002086",
+		},
+		{
+			suci: "suci-0-208-93-0-2-3-039aab8376597021e855679a9778ea0b67396e68c66d" +
+				"f32c0f41e9acca2da9b9d146a33fc2716ac7dae96aa30a4d",
+			expectErr: true,
+		},
+	}
+	for i, tc := range testCases {
+		supi, err := ToSupi(tc.suci, suciProfiles)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("TC%d fail: expected error, got supi[%s]\n", i, supi)
+			}
+		} else if err != nil {
+			t.Errorf("TC%d fail: err[%v]\n", i, err)
+		} else if supi != tc.expectedSupi {
+			t.Errorf("TC%d fail: supi[%s], expected[%s]\n", i, supi, tc.expectedSupi)
+		}
+	}
+}
+
+func TestRoutingIndicator(t *testing.T) {
+	testCases := []struct {
+		suciOrSupi               string
+		expectedRoutingIndicator string
+		expectedOk               bool
+	}{
+		{"suci-0-208-93-0-0-0-00007487", "0", true},
+		{"suci-0-208-93-1234-0-0-00007487", "1234", true},
+		{"imsi-2089300007487", "", false},
+	}
+	for i, tc := range testCases {
+		routingIndicator, ok := RoutingIndicator(tc.suciOrSupi)
+		if ok != tc.expectedOk || routingIndicator != tc.expectedRoutingIndicator {
+			t.Errorf("TC%d fail: routingIndicator[%s] ok[%t], expected[%s] [%t]\n",
+				i, routingIndicator, ok, tc.expectedRoutingIndicator, tc.expectedOk)
+		}
+	}
+}